@@ -15,6 +15,7 @@ type aliasConfig struct {
 	AllArgs               string            `yaml:"allArgs"`
 	AllExecs              map[string]string `yaml:"allExecs"`
 	DefaultAdditionalArgs string            `yaml:"defaultAdditionalArgs"`
+	ProfileTemplate       string            `yaml:"profileTemplate"`
 	Teams                 []team            `yaml:"teams"`
 }
 
@@ -25,10 +26,23 @@ type team struct {
 }
 
 type account struct {
-	AccountId string            `yaml:"accountId"`
-	Name      string            `yaml:"name"`
-	Roles     []string          `yaml:"roles"`
-	Execs     map[string]string `yaml:"execs"`
+	AccountId            string            `yaml:"accountId"`
+	Name                 string            `yaml:"name"`
+	Roles                []string          `yaml:"roles"`
+	Execs                map[string]string `yaml:"execs"`
+	IntermediateDuration int64             `yaml:"intermediateDuration"`
+	TargetDuration       int64             `yaml:"targetDuration"`
+}
+
+// durations are optional, sts limits apply to the ones given
+func (a account) validate() error {
+	if a.IntermediateDuration != 0 && (a.IntermediateDuration < 900 || a.IntermediateDuration > 129600) {
+		return fmt.Errorf("Invalid intermediateDuration of account %s: %d, must be between 900 and 129600 seconds", a.Name, a.IntermediateDuration)
+	}
+	if a.TargetDuration != 0 && (a.TargetDuration < 900 || a.TargetDuration > 43200) {
+		return fmt.Errorf("Invalid targetDuration of account %s: %d, must be between 900 and 43200 seconds", a.Name, a.TargetDuration)
+	}
+	return nil
 }
 
 type templateVars struct {
@@ -63,6 +77,9 @@ func generateAliases(w io.Writer, path string) error {
 		if err := yaml.Unmarshal(bytes, c); err != nil {
 			return err
 		}
+		if err := validateProfileTemplate(c.ProfileTemplate, "team", "name", "account", "role"); err != nil {
+			return err
+		}
 
 		for _, team := range c.Teams {
 			if err := generateAliasTeam(w, c, team); err != nil {
@@ -83,6 +100,9 @@ func generateAliasTeam(w io.Writer, config *aliasConfig, team team) error {
 }
 
 func generateAliasAccount(w io.Writer, config *aliasConfig, team team, account account) error {
+	if err := account.validate(); err != nil {
+		return err
+	}
 	if tpl, err := template.New("aliases").Option("missingkey=error").Parse(aliasTemplate); err != nil {
 		return err
 	} else {
@@ -96,6 +116,14 @@ func generateAliasAccount(w io.Writer, config *aliasConfig, team team, account a
 func generateAliasRole(w io.Writer, config *aliasConfig, team team, account account, role string, tpl *template.Template) {
 	re := regexp.MustCompile(`[^a-zA-Z0-9_]`)
 	profileName := strings.ToLower(team.Name + "-" + account.Name + "-" + re.ReplaceAllString(role, "-"))
+	if config.ProfileTemplate != "" {
+		profileName = strings.ToLower(expandProfileTemplate(config.ProfileTemplate, map[string]string{
+			"team":    team.Name,
+			"name":    account.Name,
+			"account": account.AccountId,
+			"role":    re.ReplaceAllString(role, "-"),
+		}))
+	}
 	args := config.AllArgs
 	if team.AdditionalArgs != "" {
 		args += " " + team.AdditionalArgs
@@ -105,6 +133,12 @@ func generateAliasRole(w io.Writer, config *aliasConfig, team team, account acco
 	args += " -account '" + account.AccountId + "'"
 	args += " -target-role '" + role + "'"
 	args += " -target-profile '" + profileName + "'"
+	if account.IntermediateDuration != 0 {
+		args += fmt.Sprintf(" -intermediate-duration %d", account.IntermediateDuration)
+	}
+	if account.TargetDuration != 0 {
+		args += fmt.Sprintf(" -target-duration %d", account.TargetDuration)
+	}
 
 	baseArgs := args
 
@@ -135,3 +169,58 @@ func generateExecs(w io.Writer, baseArgs string, profileName string, tpl *templa
 		tpl.Execute(w, t)
 	}
 }
+
+// lines of a and b prefixed with - and + where they differ, based on their longest common subsequence
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+a[i])
+			i++
+		default:
+			diff = append(diff, "+"+b[j])
+			j++
+		}
+	}
+	return diff
+}
+
+// compare aliases generated from configPath with the ones in aliasesPath, differences are written to w
+func checkAliases(w io.Writer, configPath, aliasesPath string) (bool, error) {
+	expected, err := ioutil.ReadFile(aliasesPath)
+	if err != nil {
+		return false, err
+	}
+	generated := new(strings.Builder)
+	if err := generateAliases(generated, configPath); err != nil {
+		return false, err
+	}
+	if string(expected) == generated.String() {
+		return true, nil
+	}
+	fmt.Fprintf(w, "--- %s\n+++ generated from %s\n", aliasesPath, configPath)
+	for _, line := range diffLines(strings.Split(string(expected), "\n"), strings.Split(generated.String(), "\n")) {
+		fmt.Fprintln(w, line)
+	}
+	return false, nil
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,3 +31,84 @@ func TestAliases_Generate(t *testing.T) {
 	assert.Equal(t, len(expected), len(actualString))
 	assert.Equal(t, expectedString, actualString)
 }
+
+func TestAliases_GenerateWithProfileTemplate(t *testing.T) {
+	aliasConfig := path.Join(os.TempDir(), "swamp-alias-config-test.yaml")
+	defer os.Remove(aliasConfig)
+	ioutil.WriteFile(aliasConfig, []byte(`profileTemplate: '{account}-{role}'
+teams:
+- name: team1
+  accounts:
+  - accountId: '123456789012'
+    name: live
+    roles:
+    - users/Admin
+`), 0644)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, generateAliases(buf, aliasConfig))
+	assert.Contains(t, buf.String(), "function swamp-123456789012-users-admin() {")
+	assert.Contains(t, buf.String(), "-target-profile '123456789012-users-admin'")
+
+	ioutil.WriteFile(aliasConfig, []byte("profileTemplate: '{region}-{role}'\n"), 0644)
+	assert.Error(t, generateAliases(new(bytes.Buffer), aliasConfig))
+}
+
+func TestAliases_DiffLines(t *testing.T) {
+	assert.Empty(t, diffLines([]string{"a", "b"}, []string{"a", "b"}))
+	assert.Equal(t, []string{"-b", "+x", "+d"}, diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"}))
+}
+
+func TestAliases_Check(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	ok, err := checkAliases(buf, "example/config.yaml", "example/bash_aliases.sh")
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, buf.String())
+}
+
+func TestAliases_CheckDrift(t *testing.T) {
+	aliasesPath := path.Join(os.TempDir(), "swamp-aliases-drift.sh")
+	defer os.Remove(aliasesPath)
+	expected, _ := ioutil.ReadFile("example/bash_aliases.sh")
+	ioutil.WriteFile(aliasesPath, bytes.Replace(expected, []byte("swamp-"), []byte("old-"), 1), 0600)
+	buf := new(bytes.Buffer)
+
+	ok, err := checkAliases(buf, "example/config.yaml", aliasesPath)
+
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, buf.String(), "--- "+aliasesPath+"\n+++ generated from example/config.yaml\n")
+	assert.Contains(t, buf.String(), "\n-function old-")
+	assert.Contains(t, buf.String(), "\n+function swamp-")
+}
+
+func TestAliases_GenerateWithDurations(t *testing.T) {
+	aliasConfig := path.Join(os.TempDir(), "swamp-alias-config-test.yaml")
+	defer os.Remove(aliasConfig)
+	ioutil.WriteFile(aliasConfig, []byte(`teams:
+- name: team1
+  accounts:
+  - accountId: '123456789012'
+    name: live
+    intermediateDuration: 28800
+    targetDuration: 7200
+    roles:
+    - admin
+`), 0644)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, generateAliases(buf, aliasConfig))
+	assert.Contains(t, buf.String(), "-target-profile 'team1-live-admin' -intermediate-duration 28800 -target-duration 7200 \"${@}\"")
+
+	ioutil.WriteFile(aliasConfig, []byte(`teams:
+- name: team1
+  accounts:
+  - accountId: '123456789012'
+    name: live
+    targetDuration: 86400
+`), 0644)
+	assert.EqualError(t, generateAliases(new(bytes.Buffer), aliasConfig), "Invalid targetDuration of account live: 86400, must be between 900 and 43200 seconds")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+var shellSafePattern = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+func shellQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
+// aws cli command equivalent to input, the mfa token and external ids read from the environment are left as placeholders
+func assumeRoleCommand(input *sts.AssumeRoleInput, maskExternalId bool, profile, region string) string {
+	args := []string{"aws", "sts", "assume-role"}
+	add := func(name, value string) {
+		args = append(args, name, shellQuote(value))
+	}
+	add("--role-arn", *input.RoleArn)
+	add("--role-session-name", *input.RoleSessionName)
+	if input.DurationSeconds != nil {
+		args = append(args, "--duration-seconds", fmt.Sprint(*input.DurationSeconds))
+	}
+	for _, arn := range input.PolicyArns {
+		add("--policy-arns", "arn="+*arn.Arn)
+	}
+	if input.Policy != nil {
+		add("--policy", *input.Policy)
+	}
+	for _, tag := range input.Tags {
+		add("--tags", "Key="+*tag.Key+",Value="+*tag.Value)
+	}
+	if input.ExternalId != nil && maskExternalId {
+		args = append(args, "--external-id", "<external-id>")
+	} else if input.ExternalId != nil {
+		add("--external-id", *input.ExternalId)
+	}
+	if input.SourceIdentity != nil {
+		add("--source-identity", *input.SourceIdentity)
+	}
+	if input.SerialNumber != nil {
+		add("--serial-number", *input.SerialNumber)
+		args = append(args, "--token-code", "<mfa-token>")
+	}
+	if profile != "" {
+		add("--profile", profile)
+	}
+	if region != "" {
+		add("--region", region)
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssumeCommand_AssumeRoleCommand(t *testing.T) {
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::1234567890:role/some-role"),
+		RoleSessionName: aws.String("some-user"),
+		DurationSeconds: aws.Int64(3600),
+		Tags:            toStsTags(map[string]string{"team": "some team"}),
+		SerialNumber:    aws.String("arn:aws:iam::1234567890:mfa/some-user"),
+		TokenCode:       aws.String("123456"),
+	}
+
+	assert.Equal(t, "aws sts assume-role --role-arn arn:aws:iam::1234567890:role/some-role --role-session-name some-user --duration-seconds 3600 "+
+		"--tags 'Key=team,Value=some team' --serial-number arn:aws:iam::1234567890:mfa/some-user --token-code <mfa-token> --profile session-token --region eu-central-1",
+		assumeRoleCommand(input, false, "session-token", "eu-central-1"))
+}
+
+func TestAssumeCommand_ShellQuote(t *testing.T) {
+	assert.Equal(t, "some-value", shellQuote("some-value"))
+	assert.Equal(t, `'it'"'"'s'`, shellQuote("it's"))
+}
+
+func TestAssumeCommand_AssumeRoleCommandWithPolicy(t *testing.T) {
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::1234567890:role/some-role"),
+		RoleSessionName: aws.String("some-user"),
+		Policy:          aws.String(`{"Version":"2012-10-17"}`),
+	}
+
+	assert.Equal(t, `aws sts assume-role --role-arn arn:aws:iam::1234567890:role/some-role --role-session-name some-user --policy '{"Version":"2012-10-17"}'`,
+		assumeRoleCommand(input, false, "", ""))
+}
+
+func TestAssumeCommand_AssumeRoleCommandWithExternalId(t *testing.T) {
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::1234567890:role/some-role"),
+		RoleSessionName: aws.String("some-user"),
+		ExternalId:      aws.String("some-external-id"),
+	}
+
+	assert.Equal(t, "aws sts assume-role --role-arn arn:aws:iam::1234567890:role/some-role --role-session-name some-user --external-id some-external-id",
+		assumeRoleCommand(input, false, "", ""))
+	assert.Equal(t, "aws sts assume-role --role-arn arn:aws:iam::1234567890:role/some-role --role-session-name some-user --external-id <external-id>",
+		assumeRoleCommand(input, true, "", ""))
+}
@@ -0,0 +1,17 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func fileAtime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec))
+	}
+	return fi.ModTime()
+}
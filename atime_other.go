@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// access time is not available, treat the file as used
+func fileAtime(fi os.FileInfo) time.Time {
+	return time.Now()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicWrite_WriteFileAtomic(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "swamp-test-atomic")
+	defer os.RemoveAll(dir)
+	p := path.Join(dir, "credentials")
+
+	assert.NoError(t, writeFileAtomic(p, 0600, func(w io.Writer) error {
+		_, err := io.WriteString(w, "[some-profile]\n")
+		return err
+	}))
+
+	b, _ := ioutil.ReadFile(p)
+	assert.Equal(t, "[some-profile]\n", string(b))
+	fi, _ := os.Stat(p)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+}
+
+func TestAtomicWrite_PartialWriteKeepsFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "swamp-test-atomic")
+	defer os.RemoveAll(dir)
+	p := path.Join(dir, "credentials")
+	ioutil.WriteFile(p, []byte("[some-profile]\naws_access_key_id = some-access-key\n"), 0640)
+
+	err := writeFileAtomic(p, 0600, func(w io.Writer) error {
+		io.WriteString(w, "[some-pro")
+		return errors.New("disk full")
+	})
+
+	assert.Error(t, err)
+	b, _ := ioutil.ReadFile(p)
+	assert.Equal(t, "[some-profile]\naws_access_key_id = some-access-key\n", string(b))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+
+	assert.NoError(t, writeFileAtomic(p, 0600, func(w io.Writer) error { return nil }))
+	fi, _ := os.Stat(p)
+	assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+}
+
+func TestAtomicWrite_KeepsSymlink(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "swamp-test-atomic")
+	defer os.RemoveAll(dir)
+	os.Mkdir(path.Join(dir, "dotfiles"), 0700)
+	target := path.Join(dir, "dotfiles", "credentials")
+	ioutil.WriteFile(target, []byte("[old-profile]\n"), 0600)
+	link := path.Join(dir, "credentials")
+	os.Symlink(target, link)
+
+	assert.NoError(t, writeFileAtomic(link, 0600, func(w io.Writer) error {
+		_, err := io.WriteString(w, "[some-profile]\n")
+		return err
+	}))
+
+	fi, _ := os.Lstat(link)
+	assert.True(t, fi.Mode()&os.ModeSymlink != 0)
+	b, _ := ioutil.ReadFile(target)
+	assert.Equal(t, "[some-profile]\n", string(b))
+	files, _ := ioutil.ReadDir(path.Join(dir, "dotfiles"))
+	assert.Len(t, files, 1)
+}
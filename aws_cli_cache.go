@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	MFA_CACHE_SCOPE_SWAMP   = "swamp"
+	MFA_CACHE_SCOPE_AWS_CLI = "aws-cli"
+	// the aws cli refreshes credentials expiring within 15 minutes
+	AWS_CLI_CACHE_MARGIN = 15 * time.Minute
+)
+
+// assume-role response as cached by the aws cli in ~/.aws/cli/cache
+type awsCliCacheEntry struct {
+	Credentials struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      string
+	}
+}
+
+// the aws cli assumes roles only with arguments of a cli profile, sessions scoped down by policies, tags or
+// a source identity are never in its cache and must not collide with the unrestricted entry
+func awsCliCacheable(input *sts.AssumeRoleInput) bool {
+	return input.Policy == nil && len(input.PolicyArns) == 0 && len(input.Tags) == 0 && len(input.TransitiveTagKeys) == 0 && input.SourceIdentity == nil
+}
+
+// cache key of the aws cli: sha1 of the assume-role arguments given in the cli profile,
+// serialized like python's json.dumps(args, sort_keys=True). Like botocore the session name is
+// only part of the key when set explicitly, i.e. role_session_name of the cli profile.
+func awsCliCacheKey(input *sts.AssumeRoleInput, sessionNameSet bool) string {
+	args := map[string]interface{}{"RoleArn": *input.RoleArn}
+	if input.SerialNumber != nil {
+		args["SerialNumber"] = *input.SerialNumber
+	}
+	if input.ExternalId != nil {
+		args["ExternalId"] = *input.ExternalId
+	}
+	if input.DurationSeconds != nil && *input.DurationSeconds != TARGET_SESSION_TOKEN_DURATION {
+		args["DurationSeconds"] = *input.DurationSeconds
+	}
+	if sessionNameSet {
+		args["RoleSessionName"] = *input.RoleSessionName
+	}
+	var keys []string
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var fields []string
+	for _, k := range keys {
+		v, _ := json.Marshal(args[k])
+		fields = append(fields, fmt.Sprintf("%q: %s", k, v))
+	}
+	sum := sha1.Sum([]byte("{" + strings.Join(fields, ", ") + "}"))
+	return hex.EncodeToString(sum[:])
+}
+
+func getAwsCliCachePath(key string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".aws", "cli", "cache", key+".json"), nil
+}
+
+// the aws cli writes expirations like 2017-07-06T08:31:10UTC
+func parseAwsCliExpiration(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02T15:04:05MST", s)
+}
+
+// cached credentials still valid for a while, nil otherwise
+func readAwsCliCache(path string, now time.Time) *sts.Credentials {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	e := &awsCliCacheEntry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil
+	}
+	expiration, err := parseAwsCliExpiration(e.Credentials.Expiration)
+	if err != nil || expiration.Before(now.Add(AWS_CLI_CACHE_MARGIN)) {
+		return nil
+	}
+	return &sts.Credentials{
+		AccessKeyId:     aws.String(e.Credentials.AccessKeyId),
+		SecretAccessKey: aws.String(e.Credentials.SecretAccessKey),
+		SessionToken:    aws.String(e.Credentials.SessionToken),
+		Expiration:      aws.Time(expiration),
+	}
+}
+
+func writeAwsCliCache(path string, cred *sts.Credentials) error {
+	e := &awsCliCacheEntry{}
+	e.Credentials.AccessKeyId = *cred.AccessKeyId
+	e.Credentials.SecretAccessKey = *cred.SecretAccessKey
+	e.Credentials.SessionToken = *cred.SessionToken
+	e.Credentials.Expiration = cred.Expiration.UTC().Format(time.RFC3339)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAwsCliCache_Key(t *testing.T) {
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::123456789012:role/admin"),
+		RoleSessionName: aws.String("some-user"),
+		DurationSeconds: aws.Int64(TARGET_SESSION_TOKEN_DURATION),
+		SerialNumber:    aws.String("arn:aws:iam::123456789012:mfa/some-user"),
+	}
+
+	// python -c 'import json,hashlib; print(hashlib.sha1(json.dumps({"RoleArn": "...", "SerialNumber": "..."}, sort_keys=True).encode()).hexdigest())'
+	assert.Equal(t, "9582f1f149bb338cadea690e167d620e01ea9e58", awsCliCacheKey(input, false))
+	// botocore keeps RoleSessionName in the key when role_session_name is set in the cli profile
+	assert.Equal(t, "8e00ee46d11dc1b95a008914de012c8d57fc83f6", awsCliCacheKey(input, true))
+}
+
+func TestAwsCliCache_Cacheable(t *testing.T) {
+	input := &sts.AssumeRoleInput{
+		RoleArn:      aws.String("arn:aws:iam::123456789012:role/admin"),
+		SerialNumber: aws.String("arn:aws:iam::123456789012:mfa/some-user"),
+		ExternalId:   aws.String("some-external-id"),
+	}
+	assert.True(t, awsCliCacheable(input))
+
+	input.PolicyArns = toStsPolicyArns([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"})
+	assert.False(t, awsCliCacheable(input))
+
+	input.PolicyArns = nil
+	input.Tags = toStsTags(map[string]string{"team": "platform"})
+	assert.False(t, awsCliCacheable(input))
+
+	input.Tags = nil
+	input.Policy = aws.String(`{"Version":"2012-10-17","Statement":[]}`)
+	assert.False(t, awsCliCacheable(input))
+
+	input.Policy = nil
+	input.SourceIdentity = aws.String("john.doe")
+	assert.False(t, awsCliCacheable(input))
+}
+
+func TestAwsCliCache_ReadAndWrite(t *testing.T) {
+	cachePath := path.Join(os.TempDir(), "swamp-test-cli-cache", "some-key.json")
+	defer os.RemoveAll(path.Dir(cachePath))
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	cred := testCredentials()
+	cred.SetExpiration(time.Date(2017, 7, 6, 9, 0, 0, 0, time.UTC))
+
+	assert.Nil(t, readAwsCliCache(cachePath, now))
+	assert.NoError(t, writeAwsCliCache(cachePath, cred))
+
+	cached := readAwsCliCache(cachePath, now)
+	assert.Equal(t, "some-session-token", *cached.SessionToken)
+	assert.True(t, cred.Expiration.Equal(*cached.Expiration))
+	assert.Nil(t, readAwsCliCache(cachePath, now.Add(50*time.Minute)))
+}
+
+func TestAwsCliCache_ReadCacheWrittenByAwsCli(t *testing.T) {
+	cachePath := path.Join(os.TempDir(), "swamp-test-cli-cache.json")
+	defer os.Remove(cachePath)
+	ioutil.WriteFile(cachePath, []byte(`{"Credentials": {"AccessKeyId": "some-access-key", "SecretAccessKey": "some-secret-access-key", "SessionToken": "some-session-token", "Expiration": "2017-07-06T09:00:00UTC"}, "AssumedRoleUser": {}}`), 0600)
+
+	cached := readAwsCliCache(cachePath, time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC))
+	assert.NotNil(t, cached)
+	assert.Equal(t, "some-access-key", *cached.AccessKeyId)
+}
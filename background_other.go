@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+import (
+	"errors"
+)
+
+func startBackground(logPath string) (int, error) {
+	return 0, errors.New("Renewing in background is not supported on this platform")
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"time"
+)
+
+// A Clock provides the current time and lets the caller wait.
+// It allows replacing the real time in tests of time dependent code.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Default clock.
+var clock Clock = realClock{}
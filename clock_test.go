@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A fakeClock advances only when Sleep is called.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+func TestClock_FakeClockSleepAdvancesNow(t *testing.T) {
+	start := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	c := newFakeClock(start)
+
+	c.Sleep(time.Minute)
+
+	assert.Equal(t, start.Add(time.Minute), c.Now())
+	assert.Equal(t, []time.Duration{time.Minute}, c.sleeps)
+}
+
+func TestClock_RenewInterval(t *testing.T) {
+	config := NewSwampConfig()
+	config.targetDuration = 3600
+
+	assert.Equal(t, 30*time.Minute, renewInterval(config))
+}
+
+func TestClock_RenewSleepWithJitter(t *testing.T) {
+	config := NewSwampConfig()
+	config.targetDuration = 3600
+
+	assert.Equal(t, 30*time.Minute, renewSleep(config))
+
+	config.refreshJitter = time.Minute
+	for i := 0; i < 100; i++ {
+		d := renewSleep(config)
+		assert.True(t, d > 29*time.Minute && d <= 30*time.Minute)
+	}
+}
@@ -5,95 +5,515 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 )
 
 const (
+	ACCOUNT_PLACEHOLDER                 = "{account}"
+	PARTITION_PLACEHOLDER               = "{partition}"
+	SERIAL_PLACEHOLDER                  = "{serial}"
 	INTERMEDIATE_SESSION_TOKEN_DURATION = int64(12 * 60 * 60)
+	INTERMEDIATE_PROFILE_SUFFIX         = "-session"
 	TARGET_SESSION_TOKEN_DURATION       = int64(60 * 60)
+	TARGET_DURATION_MAX                 = "max"
 	VERSION                             = "0.12.0"
+	ON_EXPIRY_EXIT                      = "exit"
+	ON_EXPIRY_RENEW                     = "renew"
+	ON_EXPIRY_WARN                      = "warn"
+	EXEC_ENV_PROFILE                    = "profile"
+	EXEC_ENV_CREDENTIALS                = "credentials"
+	EXEC_ENV_BOTH                       = "both"
+	BACKGROUND_ENV                      = "SWAMP_BACKGROUND"
+	EXTERNAL_ID_ENV_PREFIX              = "env:"
 )
 
+var roleArnPattern = regexp.MustCompile(`^arn:(aws|aws-cn|aws-us-gov|\{partition\}):iam::`)
+var mfaArnPattern = regexp.MustCompile(`^arn:(aws|aws-cn|aws-us-gov):iam::\d{12}:mfa/[\w+=,.@/-]+$`)
+var accountIdPattern = regexp.MustCompile(`^\d{12}$`)
+var assumableRoleArnPattern = regexp.MustCompile(`^arn:(aws|aws-cn|aws-us-gov):iam::\d{12}:role/[\w+=,.@/-]+$`)
+var hardwareSerialPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{8,}$`)
+
 type SwampConfig struct {
-	aliasConfig          string
-	targetAccount        string
-	intermediateProfile  string
-	intermediateDuration int64
-	targetProfile        string
-	targetRole           string
-	targetDuration       int64
-	profile              string
-	region               string
-	tokenSerialNumber    string
-	useInstanceProfile   bool
-	renew                bool
-	exec                 string
-	mfaExec              string
-	quiet                bool
+	aliasConfig              string
+	aliasCheck               string
+	configFiles              stringListFlag
+	targetAccount            string
+	accountFromCaller        bool
+	intermediateProfile      string
+	intermediateProfileAuto  bool
+	intermediateProfileSet   bool
+	intermediateSuffix       string
+	intermediateDuration     int64
+	intermediateReuse        bool
+	targetProfile            string
+	profilePerAccount        bool
+	profileTemplate          string
+	profileAliases           stringListFlag
+	targetRole               string
+	federationName           string
+	roleChainFile            string
+	roleChain                []chainHop
+	targetDuration           int64
+	targetDurationMax        bool
+	autoClampDuration        bool
+	sourceIdentityFromSso    bool
+	sessionNameMaxLen        int
+	sessionNameHash          bool
+	reason                   string
+	printAssumeCommand       bool
+	printCallerIdentityAfter bool
+	propagationTimeout       time.Duration
+	sessionTags              stringListFlag
+	sessionTagsFile          string
+	policyArns               stringListFlag
+	policyFile               string
+	policy                   string
+	externalId               string
+	externalIdFromEnv        bool
+	profile                  string
+	chainFromProfile         string
+	baseAccountId            string
+	baseExec                 string
+	region                   string
+	regionSet                string
+	regionMap                stringListFlag
+	profileRegions           map[string]string
+	assumeRoleRegion         string
+	ignoreInvalidRegion      bool
+	strictRegion             bool
+	tokenSerialNumber        string
+	targetMfaDevice          string
+	targetTokenCode          string
+	tokenCodes               *usedTokenCodes
+	mfaCacheScope            string
+	useInstanceProfile       bool
+	onExpiry                 string
+	once                     bool
+	renewIfUsed              bool
+	renewInBackground        bool
+	backgroundLog            string
+	watchConfig              bool
+	refreshJitter            time.Duration
+	renewMaxIterations       int
+	renewFor                 time.Duration
+	warnBeforeExpiry         time.Duration
+	healthAddr               string
+	credentialServerAddr     string
+	credentialProcess        bool
+	credentialProcessSkew    time.Duration
+	vaultPath                string
+	vaultKvVersion           int
+	keychainService          string
+	opItem                   string
+	opVault                  string
+	templateFile             string
+	templateOut              string
+	k8sSecretOut             string
+	k8sSecretName            string
+	k8sNamespace             string
+	k8sSecretKeys            string
+	printEnvExport           bool
+	shell                    string
+	envPrefix                string
+	noEnvUnset               bool
+	printExpiry              string
+	jsonFd                   int
+	exec                     string
+	execEnv                  string
+	execKeepEnv              bool
+	mfaExec                  string
+	mfaExtractCode           bool
+	mfaExecShell             string
+	mfaExecEnv               stringListFlag
+	onErrorHook              string
+	totpSecret               string
+	totpSecretFile           string
+	promptTemplate           string
+	noPrompt                 bool
+	abortOnSkew              time.Duration
+	quiet                    bool
+	verbose                  bool
+	caBundle                 string
+	noVerifySsl              bool
+	quietIfValid             bool
+	minInterval              time.Duration
+	validateWrite            bool
+	sortProfiles             bool
+	managedBlock             bool
+	protectedProfiles        stringListFlag
+	force                    bool
+	credentialsBackup        bool
+	backupKeep               int
+	combinedFile             string
+	credentialsFileFormat    string
+	status                   bool
+	doctor                   bool
+	configCheck              bool
+	configDump               bool
+	printProfilePath         bool
+	mfaDevices               bool
+	listRegions              bool
+	partition                string
+	debugTrustPolicy         bool
+	configureBase            bool
+	json                     bool
+	jsonPretty               optionalBoolFlag
 }
 
 func NewSwampConfig() *SwampConfig {
 	return &SwampConfig{
-		aliasConfig:          "",
-		targetAccount:        "",
-		intermediateProfile:  "session-token",
-		intermediateDuration: INTERMEDIATE_SESSION_TOKEN_DURATION,
-		targetProfile:        "swamp",
-		targetRole:           "",
-		targetDuration:       TARGET_SESSION_TOKEN_DURATION,
-		profile:              "",
-		region:               "",
-		tokenSerialNumber:    "",
-		useInstanceProfile:   false,
-		renew:                false,
-		exec:                 "",
-		mfaExec:              "",
-		quiet:                false,
+		aliasConfig:              "",
+		aliasCheck:               "",
+		configFiles:              nil,
+		targetAccount:            "",
+		accountFromCaller:        false,
+		intermediateProfile:      "session-token",
+		intermediateProfileAuto:  false,
+		intermediateProfileSet:   false,
+		intermediateSuffix:       INTERMEDIATE_PROFILE_SUFFIX,
+		intermediateDuration:     INTERMEDIATE_SESSION_TOKEN_DURATION,
+		intermediateReuse:        true,
+		targetProfile:            "swamp",
+		profilePerAccount:        false,
+		profileTemplate:          "",
+		profileAliases:           nil,
+		targetRole:               "",
+		federationName:           "",
+		roleChainFile:            "",
+		roleChain:                nil,
+		targetDuration:           TARGET_SESSION_TOKEN_DURATION,
+		targetDurationMax:        false,
+		autoClampDuration:        false,
+		sourceIdentityFromSso:    false,
+		sessionNameMaxLen:        MAX_SESSION_NAME_LEN,
+		sessionNameHash:          false,
+		reason:                   "",
+		printAssumeCommand:       false,
+		printCallerIdentityAfter: false,
+		propagationTimeout:       0,
+		sessionTags:              nil,
+		sessionTagsFile:          "",
+		policyArns:               nil,
+		policyFile:               "",
+		policy:                   "",
+		externalId:               "",
+		externalIdFromEnv:        false,
+		profile:                  "",
+		chainFromProfile:         "",
+		baseAccountId:            "",
+		baseExec:                 "",
+		region:                   "",
+		regionSet:                "",
+		regionMap:                nil,
+		profileRegions:           nil,
+		assumeRoleRegion:         "",
+		ignoreInvalidRegion:      false,
+		strictRegion:             false,
+		tokenSerialNumber:        "",
+		targetMfaDevice:          "",
+		targetTokenCode:          "",
+		tokenCodes:               &usedTokenCodes{},
+		mfaCacheScope:            MFA_CACHE_SCOPE_SWAMP,
+		useInstanceProfile:       false,
+		onExpiry:                 ON_EXPIRY_EXIT,
+		once:                     false,
+		renewIfUsed:              false,
+		renewInBackground:        false,
+		backgroundLog:            defaultBackgroundLog(),
+		watchConfig:              false,
+		refreshJitter:            0,
+		renewMaxIterations:       0,
+		renewFor:                 0,
+		warnBeforeExpiry:         0,
+		healthAddr:               "",
+		credentialServerAddr:     "",
+		credentialProcess:        false,
+		credentialProcessSkew:    time.Minute,
+		vaultPath:                "",
+		vaultKvVersion:           2,
+		keychainService:          "",
+		opItem:                   "",
+		opVault:                  "",
+		templateFile:             "",
+		templateOut:              "",
+		k8sSecretOut:             "",
+		k8sSecretName:            "aws-credentials",
+		k8sNamespace:             "default",
+		k8sSecretKeys:            "AWS_ACCESS_KEY_ID,AWS_SECRET_ACCESS_KEY,AWS_SESSION_TOKEN",
+		printEnvExport:           false,
+		shell:                    SHELL_SH,
+		envPrefix:                "",
+		noEnvUnset:               false,
+		printExpiry:              "",
+		jsonFd:                   0,
+		exec:                     "",
+		execEnv:                  EXEC_ENV_PROFILE,
+		execKeepEnv:              false,
+		mfaExec:                  "",
+		mfaExtractCode:           false,
+		mfaExecShell:             defaultShell(),
+		mfaExecEnv:               nil,
+		onErrorHook:              "",
+		totpSecret:               "",
+		totpSecretFile:           "",
+		promptTemplate:           "Enter mfa token for {serial}: ",
+		noPrompt:                 false,
+		abortOnSkew:              0,
+		quiet:                    false,
+		verbose:                  false,
+		caBundle:                 os.Getenv("AWS_CA_BUNDLE"),
+		noVerifySsl:              false,
+		quietIfValid:             false,
+		minInterval:              0,
+		validateWrite:            false,
+		sortProfiles:             false,
+		managedBlock:             false,
+		protectedProfiles:        nil,
+		force:                    false,
+		credentialsBackup:        false,
+		backupKeep:               5,
+		combinedFile:             "",
+		credentialsFileFormat:    CREDENTIALS_FORMAT_INI,
+		status:                   false,
+		doctor:                   false,
+		configCheck:              false,
+		configDump:               false,
+		printProfilePath:         false,
+		mfaDevices:               false,
+		listRegions:              false,
+		partition:                "",
+		debugTrustPolicy:         false,
+		configureBase:            false,
+		json:                     false,
+		jsonPretty:               optionalBoolFlag{},
+	}
+}
+
+// log of the background renewal process in the user's cache directory, not in a temp directory shared with other users
+func defaultBackgroundLog() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
 	}
+	return filepath.Join(dir, "swamp", "background.log")
 }
 
 func (config *SwampConfig) isRoleArn() bool {
-	return strings.HasPrefix(config.targetRole, "arn:aws:iam::")
+	return roleArnPattern.MatchString(config.targetRole)
+}
+
+func (config *SwampConfig) isRoleArnTemplate() bool {
+	return config.isRoleArn() && strings.Contains(config.targetRole, ACCOUNT_PLACEHOLDER)
+}
+
+// partition of the configured region, defaults to the commercial aws partition
+func (config *SwampConfig) GetPartition() string {
+	return partitionForRegion(config.region)
+}
+
+func partitionForRegion(region string) string {
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return p.ID()
+	}
+	return endpoints.AwsPartitionID
+}
+
+// sts of one partition can't assume roles of another one
+func checkRolePartition(roleArn, region string) error {
+	parts := strings.SplitN(roleArn, ":", 3)
+	if len(parts) < 3 || parts[1] == PARTITION_PLACEHOLDER {
+		return nil
+	}
+	if p := partitionForRegion(region); parts[1] != p {
+		return fmt.Errorf("Target role %s is in partition %s, but region %s is in partition %s. Roles can't be assumed across partitions, use base credentials and a region of partition %s", roleArn, parts[1], region, p, parts[1])
+	}
+	return nil
+}
+
+func (config *SwampConfig) GetRegionSet() []string {
+	var regions []string
+	for _, region := range strings.Split(config.regionSet, ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+func regionalProfileName(profile, region string) string {
+	return profile + "-" + region
 }
 
 func (config *SwampConfig) GetRoleArn() *string {
 	if config.isRoleArn() {
-		return &config.targetRole
+		arn := strings.Replace(config.targetRole, ACCOUNT_PLACEHOLDER, config.targetAccount, -1)
+		arn = strings.Replace(arn, PARTITION_PLACEHOLDER, config.GetPartition(), -1)
+		return &arn
 	} else {
-		arn := fmt.Sprintf("arn:aws:iam::%s:role/%s", config.targetAccount, config.targetRole)
+		arn := fmt.Sprintf("arn:%s:iam::%s:role/%s", config.GetPartition(), config.targetAccount, config.targetRole)
 		return &arn
 	}
 }
 
 func (config *SwampConfig) SetupFlags() {
+	flag.Var(&config.configFiles, "config", "Read settings like mfa devices per profile from yaml `file`, may be repeated with later files overriding mfa devices and adding protected profiles")
 	flag.StringVar(&config.targetAccount, "account", config.targetAccount, "AWS account")
+	flag.BoolVar(&config.accountFromCaller, "account-from-caller", config.accountFromCaller, "Assume -target-role in the account of the base identity instead of -account")
 	flag.StringVar(&config.intermediateProfile, "intermediate-profile", config.intermediateProfile, "Intermediate AWS CLI profile")
+	flag.BoolVar(&config.intermediateProfileAuto, "intermediate-profile-auto", config.intermediateProfileAuto, "Name the intermediate profile after the base profile with -intermediate-profile-suffix, instead of -intermediate-profile")
+	flag.StringVar(&config.intermediateSuffix, "intermediate-profile-suffix", config.intermediateSuffix, "Suffix of the base profile naming the intermediate profile with -intermediate-profile-auto")
 	flag.Int64Var(&config.intermediateDuration, "intermediate-duration", config.intermediateDuration, "Token duration in seconds for intermediate profile")
+	flag.BoolVar(&config.intermediateReuse, "intermediate-token-reuse", config.intermediateReuse, "Reuse a still valid intermediate session token, set to false for a fresh mfa challenge every run")
 	flag.StringVar(&config.targetProfile, "target-profile", config.targetProfile, "Write this AWS CLI profile")
-	flag.StringVar(&config.targetRole, "target-role", config.targetRole, "AWS role to assume (can either be ARN or name)")
-	flag.Int64Var(&config.targetDuration, "target-duration", config.targetDuration, "Token duration in seconds for target profile")
+	flag.BoolVar(&config.sourceIdentityFromSso, "source-identity-from-sso", config.sourceIdentityFromSso, "Set source identity of assumed role to the sso user of the base session")
+	flag.IntVar(&config.sessionNameMaxLen, "role-session-name-max-len", config.sessionNameMaxLen, "Truncate the role session name derived from the caller identity to this length")
+	flag.StringVar(&config.reason, "reason", config.reason, "Append this reason, e.g. a ticket id, to the role session name so it is recorded in cloudtrail")
+	flag.BoolVar(&config.sessionNameHash, "role-session-name-hash", config.sessionNameHash, "Replace the truncated part of the role session name with a short hash to keep names unique")
+	flag.Var(&config.sessionTags, "session-tag", "Session tag key=value for assume-role, may be repeated")
+	flag.StringVar(&config.sessionTagsFile, "session-tags-file", config.sessionTagsFile, "Read session tags for assume-role from json `file`")
+	flag.Var(&config.policyArns, "policy-arn", "Managed policy arn limiting the assumed role session, may be repeated")
+	flag.StringVar(&config.policyFile, "policy", config.policyFile, "Inline session policy json `file` limiting the assumed role session, - reads it from stdin")
+	flag.BoolVar(&config.profilePerAccount, "profile-per-account", config.profilePerAccount, "Name target profile after the assumed account, e.g. acct-123456789012")
+	flag.Var(&config.profileAliases, "profile-alias", "Also write the target credentials to this profile, kept in sync on renewal, may be repeated")
+	flag.StringVar(&config.profileTemplate, "profile-template", config.profileTemplate, "Name target profile after this template resolved after assume-role, e.g. {account}-{role}-{region}")
+	flag.StringVar(&config.targetRole, "target-role", config.targetRole, "AWS role to assume (can either be ARN, ARN template with {account} and {partition} placeholders or name)")
+	flag.StringVar(&config.federationName, "federation-name", config.federationName, "Get a federation token with this `name` for the base profile's iam user instead of assuming a role, requires -policy or -policy-arn")
+	flag.StringVar(&config.roleChainFile, "assume-role-chain-file", config.roleChainFile, "Assume the roles of this yaml `file` one after another, the last one is the target role")
+	flag.Var(&targetDurationFlag{config}, "target-duration", "Token duration in seconds for target profile, max for the role's max session duration looked up via iam")
+	flag.BoolVar(&config.autoClampDuration, "auto-clamp-duration", config.autoClampDuration, "Clamp target duration to the role's max session duration, looked up via iam and cached for a day")
+	flag.BoolVar(&config.printAssumeCommand, "print-assume-command", config.printAssumeCommand, "Print the aws cli command equivalent to the assume-role call")
+	flag.DurationVar(&config.propagationTimeout, "propagation-timeout", config.propagationTimeout, "Retry assume-role failing with AccessDenied or NoSuchEntity for up to this long, e.g. 2m for roles just created")
+	flag.BoolVar(&config.printCallerIdentityAfter, "print-caller-identity-after", config.printCallerIdentityAfter, "Print arn and account of the assumed identity after writing the target profile")
+	flag.StringVar(&config.externalId, "external-id", config.externalId, "External id passed to assume-role, env:NAME reads it from environment variable NAME")
 	flag.StringVar(&config.profile, "profile", config.profile, "AWS CLI profile")
+	flag.StringVar(&config.baseAccountId, "base-account-id", config.baseAccountId, "Fail before requesting any credentials if the base identity is not in this account")
+	flag.StringVar(&config.chainFromProfile, "chain-from-profile", config.chainFromProfile, "Use this previously written target profile as base for assume-role while it is valid")
 	flag.StringVar(&config.region, "region", config.region, "AWS region")
+	flag.StringVar(&config.regionSet, "region-set", config.regionSet, "Also write target credentials to one profile per region, named <target-profile>-<region>, e.g. us-east-1,eu-west-1")
+	flag.Var(&config.regionMap, "region-map", "Region profile=region written to this target profile or alias instead of -region, may be repeated")
+	flag.StringVar(&config.assumeRoleRegion, "assume-role-region", config.assumeRoleRegion, "Use sts of this region for assume-role, defaults to -region")
+	flag.BoolVar(&config.ignoreInvalidRegion, "ignore-invalid-region", config.ignoreInvalidRegion, "Skip checking -region against the regions known to swamp")
+	flag.BoolVar(&config.strictRegion, "strict-region", config.strictRegion, "Require a region from -region, AWS_REGION or AWS_DEFAULT_REGION instead of falling back to profile, instance metadata or the global endpoint")
 	flag.StringVar(&config.tokenSerialNumber, "mfa-device", config.tokenSerialNumber, "MFA device arn")
+	flag.StringVar(&config.targetMfaDevice, "target-mfa-device", config.targetMfaDevice, "MFA device arn passed to assume-role for roles requiring a serial number")
+	flag.StringVar(&config.targetTokenCode, "target-token-code", config.targetTokenCode, "MFA token for -target-mfa-device instead of asking for it")
+	flag.StringVar(&config.mfaCacheScope, "mfa-cache-scope", config.mfaCacheScope, "Cache of credentials assumed with -target-mfa-device: swamp or aws-cli to share ~/.aws/cli/cache with the aws cli")
 	flag.BoolVar(&config.useInstanceProfile, "instance", config.useInstanceProfile, "No-op, deprecated")
-	flag.BoolVar(&config.renew, "renew", config.renew, "Renew token every duration/2")
+	flag.StringVar(&config.onExpiry, "on-expiry", config.onExpiry, "What to do when target credentials are about to expire: exit, renew or warn")
+	flag.Var(&renewFlag{config}, "renew", "Renew token every duration/2, same as -on-expiry=renew")
+	flag.BoolVar(&config.once, "once", config.once, "Run once and exit, the default made explicit, mutual exclusive with -renew")
+	flag.DurationVar(&config.refreshJitter, "refresh-jitter", config.refreshJitter, "Renew up to this duration earlier at random to spread renewals of many hosts, e.g. 60s")
+	flag.IntVar(&config.renewMaxIterations, "renew-max-iterations", config.renewMaxIterations, "Stop renewing and exit after this many runs, 0 means no limit")
+	flag.DurationVar(&config.renewFor, "renew-for", config.renewFor, "Stop renewing and exit when the next renewal would be this long after the start, e.g. 8h, 0 means no limit")
+	flag.DurationVar(&config.warnBeforeExpiry, "warn-before-expiry", config.warnBeforeExpiry, "Show a desktop notification this long before target credentials expire in interactive renew mode, e.g. 5m")
+	flag.BoolVar(&config.renewIfUsed, "renew-if-used", config.renewIfUsed, "Pause renewing while written credentials are not read by anyone")
+	flag.BoolVar(&config.watchConfig, "watch-config", config.watchConfig, "Re-read -config, -assume-role-chain-file and -policy files before each renewal, invalid changes are logged and ignored")
+	flag.StringVar(&config.credentialServerAddr, "credential-server-addr", config.credentialServerAddr, "Serve target credentials in ecs container format on this address in renew mode instead of writing target profile, e.g. 127.0.0.1:9911")
+	flag.BoolVar(&config.credentialProcess, "credential-process", config.credentialProcess, "Print target credentials as json for credential_process in .aws/config, cached until they expire")
+	flag.DurationVar(&config.credentialProcessSkew, "credential-process-skew", config.credentialProcessSkew, "Report expiration of -credential-process output this much earlier to account for clock skew")
+	flag.StringVar(&config.vaultPath, "vault-path", config.vaultPath, "Also write target credentials to this vault kv path, using VAULT_ADDR and VAULT_TOKEN")
+	flag.IntVar(&config.vaultKvVersion, "vault-kv-version", config.vaultKvVersion, "Version of the vault kv secrets engine, 1 or 2")
+	flag.StringVar(&config.opItem, "op-item", config.opItem, "Also write target credentials to this 1password `item` with the op cli, it's created if missing")
+	flag.StringVar(&config.opVault, "op-vault", config.opVault, "1password vault of -op-item, defaults to the op cli's default vault")
+	flag.StringVar(&config.templateFile, "template-file", config.templateFile, "Also render target credentials with this go text/template `file`")
+	flag.StringVar(&config.templateOut, "template-out", config.templateOut, "Write rendered -template-file to this `file`")
+	flag.StringVar(&config.k8sSecretOut, "k8s-secret-out", config.k8sSecretOut, "Also write target credentials as kubernetes secret manifest to this `file`")
+	flag.StringVar(&config.k8sSecretName, "k8s-secret-name", config.k8sSecretName, "Name of the kubernetes secret written to -k8s-secret-out")
+	flag.StringVar(&config.k8sNamespace, "k8s-namespace", config.k8sNamespace, "Namespace of the kubernetes secret written to -k8s-secret-out")
+	flag.StringVar(&config.k8sSecretKeys, "k8s-secret-keys", config.k8sSecretKeys, "Comma separated secret keys for access key id, secret access key and session token")
+	flag.BoolVar(&config.printEnvExport, "print-env-export", config.printEnvExport, "Print statements exporting target credentials for eval in shell, other output goes to stderr")
+	flag.StringVar(&config.shell, "shell", config.shell, "Syntax of -print-env-export: sh, fish or powershell")
+	flag.StringVar(&config.envPrefix, "env-prefix", config.envPrefix, "Prefix of variables printed by -print-env-export, e.g. PROD_ for PROD_AWS_ACCESS_KEY_ID")
+	flag.BoolVar(&config.noEnvUnset, "no-env-unset", config.noEnvUnset, "Do not unset AWS_PROFILE and AWS_DEFAULT_PROFILE with -print-env-export")
+	flag.IntVar(&config.jsonFd, "json-fd", config.jsonFd, "Write profile, role, access key id and expiration of each run as json line to this inherited file descriptor, messages go to stderr")
+	flag.StringVar(&config.printExpiry, "print-expiry", config.printExpiry, "Print expiration of target credentials to stdout as epoch, rfc3339 or human, other output goes to stderr")
+	flag.StringVar(&config.healthAddr, "health-addr", config.healthAddr, "Serve /healthz and /readyz on this address in renew mode, e.g. :8080")
+	flag.StringVar(&config.totpSecret, "totp-secret", config.totpSecret, "Compute mfa token from this totp secret or otpauth uri, beware of storing the seed")
+	flag.StringVar(&config.totpSecretFile, "totp-secret-file", config.totpSecretFile, "Compute mfa token from totp secret or otpauth uri read from `file`")
+	flag.StringVar(&config.promptTemplate, "prompt-template", config.promptTemplate, "Prompt shown when asking for the mfa token, {serial} is replaced by the mfa device")
+	flag.BoolVar(&config.noPrompt, "no-prompt", config.noPrompt, "Read the mfa token without showing a prompt")
+	flag.DurationVar(&config.abortOnSkew, "abort-on-skew", config.abortOnSkew, "Exit with code 3 before using an mfa token code if the local clock is off by more than this `duration` compared to sts, e.g. 30s")
+	flag.BoolVar(&config.validateWrite, "validate-write", config.validateWrite, "Re-read credentials file after writing and verify written profiles")
+	flag.BoolVar(&config.sortProfiles, "sort-profiles", config.sortProfiles, "Sort profiles in credentials file by name when writing")
+	flag.BoolVar(&config.managedBlock, "credentials-managed-block", config.managedBlock, "Write profiles between # BEGIN swamp-managed and # END swamp-managed of the credentials file, leaving the rest untouched")
+	flag.Var(&config.protectedProfiles, "protected-profile", "Refuse to overwrite this hand-maintained profile, may be repeated and added to by protectedProfiles in -config")
+	flag.BoolVar(&config.force, "force", config.force, "Overwrite protected profiles anyway")
+	flag.StringVar(&config.combinedFile, "combined-file", config.combinedFile, "Write profiles to this single `file` holding credentials and [profile X] config sections, for AWS_CONFIG_FILE and AWS_SHARED_CREDENTIALS_FILE")
+	flag.StringVar(&config.credentialsFileFormat, "credentials-file-format", config.credentialsFileFormat, "Format of the target profiles written: ini to the credentials file or json to credentials.json next to it")
+	flag.BoolVar(&config.credentialsBackup, "credentials-backup", config.credentialsBackup, "Copy the credentials file to a timestamped backup next to it before the first write of a run")
+	flag.IntVar(&config.backupKeep, "backup-keep", config.backupKeep, "Number of credentials file backups to keep")
 	flag.BoolVar(&config.quiet, "quiet", config.quiet, "Suppress output")
+	flag.BoolVar(&config.verbose, "verbose", config.verbose, "Print timings of all aws requests")
+	flag.StringVar(&config.caBundle, "ca-bundle", config.caBundle, "Trust the certificates in this pem `file` for aws requests, defaults to AWS_CA_BUNDLE")
+	flag.BoolVar(&config.noVerifySsl, "no-verify-ssl", config.noVerifySsl, "Do not verify tls certificates of aws endpoints, only for testing against emulators")
+	flag.BoolVar(&config.quietIfValid, "quiet-if-valid", config.quietIfValid, "Keep still valid profiles and suppress output if nothing changed")
+	flag.DurationVar(&config.minInterval, "min-interval", config.minInterval, "Exit right away if swamp ran for the same target less than this duration ago, e.g. 5s")
+	flag.BoolVar(&config.status, "status", config.status, "List profiles written by swamp and their expiry")
+	flag.BoolVar(&config.doctor, "doctor", config.doctor, "Check setup of base profile, region, mfa device and target role")
+	flag.BoolVar(&config.printProfilePath, "print-profile-path", config.printProfilePath, "Print the absolute paths of the credentials file written and the config file read and exit")
+	flag.BoolVar(&config.configCheck, "config-check", config.configCheck, "Validate -config, -assume-role-chain-file, referenced profiles and flags without calling aws and exit")
+	flag.BoolVar(&config.configDump, "config-dump", config.configDump, "Print the effective configuration after applying -config files with secrets redacted and exit")
+	flag.BoolVar(&config.mfaDevices, "mfa-devices", config.mfaDevices, "List serial numbers of the mfa devices of the base profile's user")
+	flag.BoolVar(&config.listRegions, "list-regions", config.listRegions, "List the regions known to swamp for -partition and exit")
+	flag.StringVar(&config.partition, "partition", config.partition, "Partition for -list-regions: aws, aws-cn or aws-us-gov, defaults to the partition of -region")
+	flag.BoolVar(&config.configureBase, "configure-base", config.configureBase, "Write static keys from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or stdin into the base profile")
+	flag.BoolVar(&config.debugTrustPolicy, "debug-trust-policy", config.debugTrustPolicy, "Print the trust policy of the target role next to the caller instead of assuming it, needs iam:GetRole")
+	flag.BoolVar(&config.json, "json", config.json, "Print -status and -config-dump output as json")
+	flag.Var(&config.jsonPretty, "json-pretty", "Indent json output, defaults to true for -status and -config-dump and to false for -credential-process and -json-fd")
+	flag.StringVar(&config.mfaExec, "mfa-exec", config.mfaExec, "Executable command for obtaining mfa-device token")
+	flag.BoolVar(&config.mfaExtractCode, "mfa-extract-code", config.mfaExtractCode, "Use the first 6-digit code from -mfa-exec output, ignoring other output")
+	flag.StringVar(&config.mfaExecShell, "mfa-exec-shell", config.mfaExecShell, "Shell running -mfa-exec, e.g. /bin/bash or cmd, none runs the command split at whitespace without a shell")
+	flag.Var(&config.mfaExecEnv, "mfa-exec-env", "Environment variable key=value added for -mfa-exec, may be repeated")
 	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
 		// platform specific flags
 		flag.StringVar(&config.aliasConfig, "alias-config", config.aliasConfig, "Generate aliases from yaml `file`")
+		flag.StringVar(&config.aliasCheck, "alias-check", config.aliasCheck, "Compare aliases generated with -alias-config to this `file` instead of printing them, exit non-zero and print a diff on drift")
 		flag.StringVar(&config.exec, "exec", config.exec, "Execute this commend with AWS_PROFILE set to target protile")
-		flag.StringVar(&config.mfaExec, "mfa-exec", config.mfaExec, "Executable command for obtaining mfa-device token")
+		flag.StringVar(&config.execEnv, "exec-env", config.execEnv, "Environment for -exec: profile sets AWS_PROFILE, credentials sets AWS_ACCESS_KEY_ID etc. and AWS_REGION, both sets all")
+		flag.BoolVar(&config.execKeepEnv, "exec-keep-env", config.execKeepEnv, "Keep AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN already set for -exec with -exec-env=profile")
+		flag.StringVar(&config.onErrorHook, "on-error-hook", config.onErrorHook, "Run this command on errors with SWAMP_ERROR_STEP and SWAMP_ERROR set")
+		flag.BoolVar(&config.renewInBackground, "renew-in-background", config.renewInBackground, "Renew in a detached background process after the first successful run")
+		flag.StringVar(&config.backgroundLog, "background-log", config.backgroundLog, "Append output of the background renewal process to this `file`")
+		flag.StringVar(&config.baseExec, "base-exec", config.baseExec, "Executable command returning base credentials as json, used instead of -profile")
+	}
+	if runtime.GOOS == "darwin" {
+		flag.StringVar(&config.keychainService, "keychain-service", config.keychainService, "Also write target credentials as generic password of this service with the target profile as account to the macOS keychain")
 	}
 	flag.Usage = flagUsage
 }
 
 func (config *SwampConfig) validateDefaultFlags() error {
-	if config.targetRole != "" || config.tokenSerialNumber == "" {
+	if config.aliasCheck != "" {
+		return errors.New("Alias check requires -alias-config")
+	}
+
+	if config.targetRole == "" && config.tokenSerialNumber == "" && config.federationName == "" {
+		return errors.New("Nothing to do, missing -target-role or -assume-role-chain-file to assume a role, -mfa-device for a session token only or -federation-name for a federation token")
+	}
+
+	if config.federationName != "" {
+		if err := config.validateFederation(); err != nil {
+			return err
+		}
+	} else if config.targetRole != "" || config.tokenSerialNumber == "" {
 		if err := checkStringFlagNotEmpty("target-profile", config.targetProfile); err != nil {
 			return err
 		}
-		if !config.isRoleArn() {
+		if config.accountFromCaller {
+			if config.targetAccount != "" {
+				return errors.New("Account from caller and account are mutual exclusive")
+			}
+			if config.isRoleArn() && !config.isRoleArnTemplate() {
+				return errors.New("Account from caller requires a target role name or arn template")
+			}
+		} else if !config.isRoleArn() || config.isRoleArnTemplate() {
 			if err := checkStringFlagNotEmpty("account", config.targetAccount); err != nil {
 				return err
 			}
@@ -104,26 +524,475 @@ func (config *SwampConfig) validateDefaultFlags() error {
 		}
 	}
 
+	if config.strictRegion && !hasExplicitRegion(config) {
+		return errors.New("Strict region requires -region, AWS_REGION or AWS_DEFAULT_REGION")
+	}
+
+	profileRegions, err := config.GetProfileRegions()
+	if err != nil {
+		return err
+	}
+	if !config.ignoreInvalidRegion {
+		regions := config.GetRegionSet()
+		for _, region := range profileRegions {
+			regions = append(regions, region)
+		}
+		for _, region := range []string{config.region, config.assumeRoleRegion} {
+			if region != "" {
+				regions = append(regions, region)
+			}
+		}
+		for _, region := range regions {
+			if err := validateRegion(region); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, alias := range config.profileAliases {
+		if alias == config.targetProfile || alias == config.intermediateProfile || alias == guessCurrentProfile(config) {
+			return fmt.Errorf("Profile alias %s must differ from base, intermediate and target profile", alias)
+		}
+		if config.credentialServerAddr != "" {
+			return errors.New("Profile alias and credential server are mutual exclusive")
+		}
+	}
+
+	if config.profileTemplate != "" {
+		if config.profilePerAccount {
+			return errors.New("Profile template and profile per account are mutual exclusive")
+		}
+		if err := validateProfileTemplate(config.profileTemplate, "account", "role", "region"); err != nil {
+			return err
+		}
+	}
+
+	if config.isRoleArn() {
+		region := config.region
+		if config.assumeRoleRegion != "" {
+			region = config.assumeRoleRegion
+		}
+		if region != "" {
+			if err := checkRolePartition(config.targetRole, region); err != nil {
+				return err
+			}
+		}
+	}
+
+	if config.chainFromProfile != "" {
+		if err := checkStringFlagNotEmpty("target-role", config.targetRole); err != nil {
+			return err
+		}
+		if config.chainFromProfile == config.targetProfile {
+			return errors.New("Chain from profile and target profile must differ")
+		}
+	}
+
+	if config.caBundle != "" && config.noVerifySsl {
+		return errors.New("Ca bundle and no verify ssl are mutual exclusive")
+	}
+
+	switch config.onExpiry {
+	case ON_EXPIRY_EXIT, ON_EXPIRY_RENEW, ON_EXPIRY_WARN:
+	default:
+		return fmt.Errorf("Invalid value for on-expiry: %s", config.onExpiry)
+	}
+	if config.once && config.onExpiry != ON_EXPIRY_EXIT {
+		return fmt.Errorf("Once and on-expiry=%s are mutual exclusive", config.onExpiry)
+	}
+
+	if config.healthAddr != "" && config.onExpiry == ON_EXPIRY_EXIT {
+		return errors.New("Health endpoint requires -renew or -on-expiry=warn")
+	}
+
+	if config.credentialServerAddr != "" {
+		if err := checkStringFlagNotEmpty("target-role", config.targetRole); err != nil {
+			return err
+		}
+		if config.onExpiry != ON_EXPIRY_RENEW {
+			return errors.New("Credential server requires -renew")
+		}
+		if config.exec != "" {
+			return errors.New("Credential server and exec are mutual exclusive")
+		}
+	}
+
+	if config.credentialProcess {
+		if err := checkStringFlagNotEmpty("target-role", config.targetRole); err != nil {
+			return err
+		}
+		if config.onExpiry != ON_EXPIRY_EXIT || config.exec != "" || config.credentialServerAddr != "" {
+			return errors.New("Credential process is mutual exclusive with renew, exec and credential server")
+		}
+		if config.credentialProcessSkew < 0 || config.credentialProcessSkew >= time.Duration(config.targetDuration)*time.Second {
+			return errors.New("Credential process skew must be between 0 and the target duration")
+		}
+	}
+
+	if config.vaultPath != "" {
+		if config.vaultKvVersion != 1 && config.vaultKvVersion != 2 {
+			return fmt.Errorf("Invalid value for vault-kv-version: %d", config.vaultKvVersion)
+		}
+		if err := checkStringFlagNotEmpty("VAULT_ADDR", os.Getenv("VAULT_ADDR")); err != nil {
+			return err
+		}
+		if err := checkStringFlagNotEmpty("VAULT_TOKEN", os.Getenv("VAULT_TOKEN")); err != nil {
+			return err
+		}
+	}
+
+	switch config.execEnv {
+	case EXEC_ENV_PROFILE, EXEC_ENV_CREDENTIALS, EXEC_ENV_BOTH:
+	default:
+		return fmt.Errorf("Invalid value for exec-env: %s", config.execEnv)
+	}
+	if config.execKeepEnv && config.execEnv != EXEC_ENV_PROFILE {
+		return errors.New("Exec keep env requires -exec-env=profile")
+	}
+	if config.quietIfValid && config.execEnv != EXEC_ENV_PROFILE {
+		return errors.New("Quiet if valid requires -exec-env=profile")
+	}
+
+	if config.renewIfUsed && config.onExpiry != ON_EXPIRY_RENEW {
+		return errors.New("Renew if used requires -renew")
+	}
+
+	switch config.credentialsFileFormat {
+	case CREDENTIALS_FORMAT_INI:
+	case CREDENTIALS_FORMAT_JSON:
+		// the aws cli and sdk can not read the json file
+		if config.combinedFile != "" || config.quietIfValid || config.validateWrite {
+			return errors.New("Credentials file format json is mutual exclusive with combined file, quiet if valid and validate write")
+		}
+		if config.exec != "" && config.execEnv != EXEC_ENV_CREDENTIALS {
+			return errors.New("Credentials file format json requires -exec-env=credentials for -exec")
+		}
+	default:
+		return fmt.Errorf("Invalid value for credentials-file-format: %s", config.credentialsFileFormat)
+	}
+
+	if config.watchConfig {
+		if config.onExpiry != ON_EXPIRY_RENEW {
+			return errors.New("Watch config requires -renew")
+		}
+		if len(config.configFiles) == 0 && config.roleChainFile == "" && config.policyFile == "" {
+			return errors.New("Watch config requires -config, -assume-role-chain-file or -policy")
+		}
+	}
+
+	if config.renewInBackground {
+		if config.onExpiry != ON_EXPIRY_RENEW {
+			return errors.New("Renew in background requires -renew")
+		}
+		if config.credentialServerAddr != "" || config.healthAddr != "" {
+			return errors.New("Renew in background is mutual exclusive with credential server and health endpoint")
+		}
+		if config.asksForTokenCode() {
+			return errors.New("Renew in background can't ask for mfa tokens, requires -mfa-exec, -totp-secret or -totp-secret-file")
+		}
+		if config.backgroundLog == "" {
+			return errors.New("Renew in background requires -background-log")
+		}
+	}
+
+	if config.opVault != "" && config.opItem == "" {
+		return errors.New("Op vault requires -op-item")
+	}
+
+	if config.abortOnSkew < 0 {
+		return fmt.Errorf("Invalid value for abort-on-skew: %v", config.abortOnSkew)
+	}
+
+	if config.minInterval < 0 {
+		return fmt.Errorf("Invalid value for min-interval: %v", config.minInterval)
+	}
+
+	if config.sessionNameMaxLen < MIN_SESSION_NAME_LEN || config.sessionNameMaxLen > MAX_SESSION_NAME_LEN {
+		return fmt.Errorf("Invalid value for role-session-name-max-len: %d", config.sessionNameMaxLen)
+	}
+	if config.reason != "" {
+		if !sessionNamePattern.MatchString(config.reason) {
+			return fmt.Errorf("Invalid value for reason: %s, only letters, digits and +=,.@_- are allowed", config.reason)
+		}
+		if len(config.reason) > config.sessionNameMaxLen-MIN_SESSION_NAME_LEN-1 {
+			return fmt.Errorf("Invalid value for reason: %s is longer than %d characters", config.reason, config.sessionNameMaxLen-MIN_SESSION_NAME_LEN-1)
+		}
+	}
+
+	if config.refreshJitter != 0 {
+		if config.onExpiry != ON_EXPIRY_RENEW {
+			return errors.New("Refresh jitter requires -renew")
+		}
+		if config.refreshJitter < 0 || config.refreshJitter >= renewInterval(config) {
+			return fmt.Errorf("Refresh jitter must be between 0 and %v", renewInterval(config))
+		}
+	}
+
+	if config.renewMaxIterations != 0 || config.renewFor != 0 {
+		if config.onExpiry != ON_EXPIRY_RENEW {
+			return errors.New("Renew max iterations and renew for require -renew")
+		}
+		if config.renewMaxIterations < 0 {
+			return fmt.Errorf("Invalid value for renew-max-iterations: %d", config.renewMaxIterations)
+		}
+		if config.renewFor < 0 {
+			return fmt.Errorf("Invalid value for renew-for: %v", config.renewFor)
+		}
+	}
+
+	if config.propagationTimeout < 0 {
+		return fmt.Errorf("Invalid value for propagation-timeout: %v", config.propagationTimeout)
+	}
+
+	if config.baseAccountId != "" && !accountIdPattern.MatchString(config.baseAccountId) {
+		return fmt.Errorf("Invalid value for base-account-id: %s", config.baseAccountId)
+	}
+
+	if config.credentialsBackup && config.backupKeep < 1 {
+		return fmt.Errorf("Invalid value for backup-keep: %d", config.backupKeep)
+	}
+
+	if config.jsonFd < 0 || (config.jsonFd > 0 && config.jsonFd < 3) {
+		return fmt.Errorf("Invalid value for json-fd: %d, use 3 or higher", config.jsonFd)
+	}
+
+	if config.warnBeforeExpiry != 0 {
+		if config.onExpiry != ON_EXPIRY_RENEW {
+			return errors.New("Warn before expiry requires -renew")
+		}
+		if config.warnBeforeExpiry < 0 {
+			return fmt.Errorf("Invalid value for warn-before-expiry: %v", config.warnBeforeExpiry)
+		}
+	}
+
+	if config.quietIfValid && config.onExpiry != ON_EXPIRY_EXIT {
+		return errors.New("Quiet if valid and renew are mutual exclusive")
+	}
+
+	if _, err := config.GetSessionTags(); err != nil {
+		return err
+	}
+
+	if config.externalId != "" {
+		if strings.HasPrefix(config.externalId, EXTERNAL_ID_ENV_PREFIX) {
+			config.externalIdFromEnv = true
+		}
+		externalId, err := resolveExternalId(config.externalId)
+		if err != nil {
+			return err
+		}
+		config.externalId = externalId
+	}
+
+	if err := config.validatePolicyFromStdin(); err != nil {
+		return err
+	}
+	if err := validatePolicyArns(config.policyArns); err != nil {
+		return err
+	}
+	if err := validateSessionPolicySize(config.policy, config.policyArns); err != nil {
+		return err
+	}
+
+	if (config.templateFile == "") != (config.templateOut == "") {
+		return errors.New("Template file and template out must be set together")
+	}
+
+	if config.k8sSecretOut != "" {
+		if _, err := parseK8sSecretKeys(config.k8sSecretKeys); err != nil {
+			return err
+		}
+	}
+
+	if config.printEnvExport && config.federationName == "" {
+		if err := checkStringFlagNotEmpty("target-role", config.targetRole); err != nil {
+			return err
+		}
+	}
+	if config.printEnvExport {
+		if config.credentialProcess {
+			return errors.New("Print env export and credential process are mutual exclusive")
+		}
+		// stdout goes to eval and must carry nothing but the export statements
+		if config.printExpiry != "" {
+			return errors.New("Print env export and print expiry are mutual exclusive")
+		}
+		if config.exec != "" {
+			return errors.New("Print env export and exec are mutual exclusive")
+		}
+		if err := validateEnvExport(config.shell, config.envPrefix); err != nil {
+			return err
+		}
+	}
+	if config.noEnvUnset && !config.printEnvExport {
+		return errors.New("No env unset requires -print-env-export")
+	}
+
+	switch config.printExpiry {
+	case "", PRINT_EXPIRY_EPOCH, PRINT_EXPIRY_RFC3339, PRINT_EXPIRY_HUMAN:
+	default:
+		return fmt.Errorf("Invalid value for print-expiry: %s", config.printExpiry)
+	}
+	if config.printExpiry != "" && config.credentialProcess {
+		return errors.New("Print expiry and credential process are mutual exclusive")
+	}
+
 	if config.useInstanceProfile {
-		fmt.Println("Option -instance is deprecated as -profile allows empty values.")
-		fmt.Println("It will be removed in future releases.")
+		printer.Println("Option -instance is deprecated as -profile allows empty values.")
+		printer.Println("It will be removed in future releases.")
 	}
 
 	if config.tokenSerialNumber != "" {
 		if err := checkStringFlagNotEmpty("intermediate-profile", config.intermediateProfile); err != nil {
 			return err
 		}
+		if config.intermediateProfileAuto && config.intermediateProfileSet {
+			return errors.New("Intermediate profile auto and intermediate profile are mutual exclusive")
+		}
+		if config.intermediateProfile == guessCurrentProfile(config) {
+			return fmt.Errorf("Intermediate profile must differ from base profile %s", config.intermediateProfile)
+		}
+		if err := validateMfaDevice("mfa-device", config.tokenSerialNumber); err != nil {
+			return err
+		}
+	}
+
+	if config.targetMfaDevice != "" {
+		if err := validateMfaDevice("target-mfa-device", config.targetMfaDevice); err != nil {
+			return err
+		}
+	}
+
+	if config.targetMfaDevice != "" {
+		if err := checkStringFlagNotEmpty("target-role", config.targetRole); err != nil {
+			return err
+		}
+	}
+
+	switch config.mfaCacheScope {
+	case MFA_CACHE_SCOPE_SWAMP:
+	case MFA_CACHE_SCOPE_AWS_CLI:
+		if err := checkStringFlagNotEmpty("target-mfa-device", config.targetMfaDevice); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Invalid value for mfa-cache-scope: %s", config.mfaCacheScope)
+	}
+
+	if config.targetTokenCode != "" {
+		if err := checkStringFlagNotEmpty("target-mfa-device", config.targetMfaDevice); err != nil {
+			return err
+		}
+		if config.onExpiry == ON_EXPIRY_RENEW {
+			return errors.New("Target token code and renew are mutual exclusive")
+		}
 	}
 
 	if config.mfaExec != "" {
-		if err := checkStringFlagNotEmpty("mfa-device", config.tokenSerialNumber); err != nil {
+		if err := config.checkMfaDeviceSet(); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(config.mfaExecShell) == "" {
+		return fmt.Errorf("Invalid value for mfa-exec-shell: %q", config.mfaExecShell)
+	}
+	if config.mfaExec != "" && strings.TrimSpace(config.mfaExec) == "" {
+		return fmt.Errorf("Invalid value for mfa-exec: %q", config.mfaExec)
+	}
+	if len(config.mfaExecEnv) > 0 && config.mfaExec == "" {
+		return errors.New("Mfa exec env requires -mfa-exec")
+	}
+	for _, env := range config.mfaExecEnv {
+		if parts := strings.SplitN(env, "=", 2); len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("Invalid value for mfa-exec-env: %s, expected key=value", env)
+		}
+	}
+
+	if config.totpSecret != "" || config.totpSecretFile != "" {
+		if err := config.checkMfaDeviceSet(); err != nil {
 			return err
 		}
+		if config.totpSecret != "" && config.totpSecretFile != "" {
+			return errors.New("Totp secret and totp secret file are mutual exclusive")
+		}
+		if config.mfaExec != "" {
+			return errors.New("Totp secret and mfa exec are mutual exclusive")
+		}
 	}
 
 	return nil
 }
 
+// serial must be the arn of a virtual mfa device or the serial number of a hardware token
+func validateMfaDevice(name, serial string) error {
+	if mfaArnPattern.MatchString(serial) || hardwareSerialPattern.MatchString(serial) {
+		return nil
+	}
+	if strings.HasPrefix(serial, "arn:") {
+		return fmt.Errorf("Invalid value for %s: %s is no mfa device arn like arn:aws:iam::123456789012:mfa/user", name, serial)
+	}
+	return fmt.Errorf("Invalid value for %s: %s is neither an mfa device arn like arn:aws:iam::123456789012:mfa/user nor the serial number of a hardware token like GAHT12345678", name, serial)
+}
+
+// external ids of the form env:NAME are read from environment variable NAME
+// to keep them out of process listings and shell history
+func resolveExternalId(externalId string) (string, error) {
+	if strings.HasPrefix(externalId, EXTERNAL_ID_ENV_PREFIX) {
+		name := strings.TrimPrefix(externalId, EXTERNAL_ID_ENV_PREFIX)
+		if externalId = os.Getenv(name); externalId == "" {
+			return "", fmt.Errorf("Environment variable %s for external id is not set", name)
+		}
+	}
+	if !externalIdPattern.MatchString(externalId) || len(externalId) < 2 || len(externalId) > 1224 {
+		return "", errors.New("Invalid external id, expected 2 to 1224 characters of a-z, A-Z, 0-9 and +=,.@:/-")
+	}
+	return externalId, nil
+}
+
+// with -intermediate-profile-auto the intermediate profile is named after the base profile, e.g. team3-session,
+// an -intermediate-profile given in fs is kept for Validate to report the conflict
+func (config *SwampConfig) DeriveIntermediateProfile(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "intermediate-profile" {
+			config.intermediateProfileSet = true
+		}
+	})
+	if config.intermediateProfileAuto && !config.intermediateProfileSet {
+		config.intermediateProfile = guessCurrentProfile(config) + config.intermediateSuffix
+	}
+}
+
+// token sources like -mfa-exec and -totp-secret need a device to obtain token codes for
+func (config *SwampConfig) checkMfaDeviceSet() error {
+	if config.targetMfaDevice != "" {
+		return nil
+	}
+	return checkStringFlagNotEmpty("mfa-device", config.tokenSerialNumber)
+}
+
+// whether an mfa token code will be read from stdin
+func (config *SwampConfig) asksForTokenCode() bool {
+	if config.mfaExec != "" || config.totpSecret != "" || config.totpSecretFile != "" {
+		return false
+	}
+	return config.tokenSerialNumber != "" || (config.targetMfaDevice != "" && config.targetTokenCode == "")
+}
+
+// a session policy read from stdin would swallow the mfa token code, checked before stdin is read
+func (config *SwampConfig) validatePolicyFromStdin() error {
+	if config.policyFile == POLICY_STDIN && config.asksForTokenCode() {
+		return errors.New("Session policy from stdin requires -mfa-exec, -totp-secret or -target-token-code as mfa tokens are read from stdin as well")
+	}
+	return nil
+}
+
+func (config *SwampConfig) validateConfigureBaseFlags() error {
+	if profile := guessCurrentProfile(config); profile == config.intermediateProfile || profile == config.targetProfile {
+		return fmt.Errorf("Base profile %s must differ from intermediate and target profile", profile)
+	}
+	return nil
+}
+
 func (config *SwampConfig) validateAliasFlags() error {
 	if _, err := os.Stat(config.aliasConfig); os.IsNotExist(err) {
 		return err
@@ -132,11 +1001,127 @@ func (config *SwampConfig) validateAliasFlags() error {
 }
 
 func (config *SwampConfig) Validate() error {
-	if config.aliasConfig == "" {
+	switch {
+	case config.status, config.doctor, config.mfaDevices, config.printProfilePath, config.listRegions, config.configDump:
+		return nil
+	case config.configCheck:
+		if len(config.configFiles) == 0 && config.roleChainFile == "" {
+			return errors.New("Config check requires -config or -assume-role-chain-file")
+		}
+		return nil
+	case config.configureBase:
+		return config.validateConfigureBaseFlags()
+	case config.aliasConfig != "":
+		return config.validateAliasFlags()
+	default:
 		return config.validateDefaultFlags()
+	}
+}
+
+// A stringListFlag collects the values of a repeated flag.
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// An optionalBoolFlag is a bool flag knowing whether it was given, so its default may depend on the mode.
+type optionalBoolFlag struct {
+	set   bool
+	value bool
+}
+
+func (f *optionalBoolFlag) String() string {
+	if f == nil || !f.set {
+		return ""
+	}
+	return strconv.FormatBool(f.value)
+}
+
+func (f *optionalBoolFlag) Set(s string) error {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	f.set = true
+	f.value = value
+	return nil
+}
+
+func (f *optionalBoolFlag) IsBoolFlag() bool {
+	return true
+}
+
+// the given value or def if the flag was not given
+func (f *optionalBoolFlag) Or(def bool) bool {
+	if f.set {
+		return f.value
+	}
+	return def
+}
+
+// A renewFlag maps the boolean -renew flag to the on-expiry policy.
+type renewFlag struct {
+	config *SwampConfig
+}
+
+func (f *renewFlag) String() string {
+	if f.config == nil {
+		return "false"
+	}
+	return strconv.FormatBool(f.config.onExpiry == ON_EXPIRY_RENEW)
+}
+
+func (f *renewFlag) Set(s string) error {
+	renew, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if renew {
+		f.config.onExpiry = ON_EXPIRY_RENEW
 	} else {
-		return config.validateAliasFlags()
+		f.config.onExpiry = ON_EXPIRY_EXIT
+	}
+	return nil
+}
+
+func (f *renewFlag) IsBoolFlag() bool { return true }
+
+// A targetDurationFlag accepts seconds or max for -target-duration.
+type targetDurationFlag struct {
+	config *SwampConfig
+}
+
+func (f *targetDurationFlag) String() string {
+	if f.config == nil {
+		return strconv.FormatInt(TARGET_SESSION_TOKEN_DURATION, 10)
+	}
+	if f.config.targetDurationMax {
+		return TARGET_DURATION_MAX
 	}
+	return strconv.FormatInt(f.config.targetDuration, 10)
+}
+
+func (f *targetDurationFlag) Set(s string) error {
+	if s == TARGET_DURATION_MAX {
+		f.config.targetDurationMax = true
+		return nil
+	}
+	duration, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	f.config.targetDuration = duration
+	f.config.targetDurationMax = false
+	return nil
 }
 
 func checkStringFlagNotEmpty(name string, f string) error {
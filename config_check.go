@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+func getSharedConfigPath() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("Error fetching home dir: %s", err)
+	}
+	return filepath.Join(usr.HomeDir, ".aws", "config"), nil
+}
+
+// names of all profiles in the shared config and credentials files, missing files are ignored
+func listSharedProfiles() (map[string]bool, error) {
+	profiles := map[string]bool{}
+	configPath, err := getSharedConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	credentialsPath, err := getCredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range []string{configPath, credentialsPath} {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		cfg, err := ini.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %s", path, err)
+		}
+		for _, name := range cfg.SectionStrings() {
+			if name == ini.DefaultSection {
+				continue
+			}
+			profiles[strings.TrimPrefix(name, "profile ")] = true
+		}
+	}
+	return profiles, nil
+}
+
+func checkProfileExists(profiles map[string]bool, profile string) checkResult {
+	r := checkResult{name: "Profile " + profile}
+	if !profiles[profile] {
+		r.err = errors.New("Profile not found in shared config or credentials file")
+		r.hint = "Add the profile to ~/.aws/config or ~/.aws/credentials."
+	}
+	return r
+}
+
+// validate config files and flags without calling aws
+func runConfigCheck(config *SwampConfig) []checkResult {
+	var results []checkResult
+
+	profiles, err := listSharedProfiles()
+	if err != nil {
+		return append(results, checkResult{name: "Shared config", err: err})
+	}
+
+	if len(config.configFiles) > 0 {
+		loaded := true
+		for _, path := range config.configFiles {
+			_, err := loadConfigFile(path)
+			results = append(results, checkResult{name: "Config file " + path, err: err})
+			loaded = loaded && err == nil
+		}
+		if loaded {
+			c, _ := loadConfigFiles(config.configFiles)
+			var names []string
+			for name := range c.MfaDevices {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				results = append(results, checkResult{
+					name: "Mfa device of profile " + name,
+					err:  validateMfaDevice("mfaDevices."+name, c.MfaDevices[name]),
+				})
+				results = append(results, checkProfileExists(profiles, name))
+			}
+			config.applyConfigFile(c)
+		}
+	}
+
+	if config.roleChainFile != "" {
+		results = append(results, checkResult{name: "Assume role chain file " + config.roleChainFile, err: config.LoadRoleChain()})
+	}
+
+	for _, profile := range []string{config.profile, config.chainFromProfile} {
+		if profile != "" {
+			results = append(results, checkProfileExists(profiles, profile))
+		}
+	}
+
+	if config.targetRole == "" && config.targetAccount == "" {
+		results = append(results, checkResult{name: "Command line flags", skipped: true})
+	} else {
+		results = append(results, checkResult{name: "Command line flags", err: config.validateDefaultFlags()})
+	}
+	return results
+}
+
+func configCheck(w io.Writer, config *SwampConfig) bool {
+	return writeCheckResults(w, runConfigCheck(config))
+}
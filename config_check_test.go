@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupConfigCheckTest(swampConfig string) (string, func()) {
+	configPath := path.Join(os.TempDir(), "swamp-config-check-aws-config")
+	credentialsPath := path.Join(os.TempDir(), "swamp-config-check-credentials")
+	swampConfigPath := path.Join(os.TempDir(), "swamp-config-check.yaml")
+	ioutil.WriteFile(configPath, []byte("[profile team3]\nregion = eu-central-1\n"), 0600)
+	ioutil.WriteFile(credentialsPath, []byte("[default]\naws_access_key_id = some-key\n"), 0600)
+	ioutil.WriteFile(swampConfigPath, []byte(swampConfig), 0600)
+	os.Setenv("AWS_CONFIG_FILE", configPath)
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsPath)
+	return swampConfigPath, func() {
+		os.Remove(configPath)
+		os.Remove(credentialsPath)
+		os.Remove(swampConfigPath)
+		os.Clearenv()
+	}
+}
+
+func TestConfigCheck_ListSharedProfiles(t *testing.T) {
+	_, cleanup := setupConfigCheckTest("")
+	defer cleanup()
+
+	profiles, err := listSharedProfiles()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"team3": true, "default": true}, profiles)
+}
+
+func TestConfigCheck_Ok(t *testing.T) {
+	path, cleanup := setupConfigCheckTest(`mfaDevices:
+  default: arn:aws:iam::123456789012:mfa/some-user
+  team3: GAHT12345678
+`)
+	defer cleanup()
+	config := NewSwampConfig()
+	config.configFiles = []string{path}
+	buf := new(bytes.Buffer)
+
+	assert.True(t, configCheck(buf, config))
+	assert.Equal(t, `[ok]   Config file `+path+`
+[ok]   Mfa device of profile default
+[ok]   Profile default
+[ok]   Mfa device of profile team3
+[ok]   Profile team3
+[skip] Command line flags
+`, buf.String())
+}
+
+func TestConfigCheck_Failures(t *testing.T) {
+	path, cleanup := setupConfigCheckTest(`mfaDevices:
+  missing: arn:aws:iam::AAAAAAAAA:mfa/some-user
+`)
+	defer cleanup()
+	config := NewSwampConfig()
+	config.configFiles = []string{path}
+	config.targetAccount = "123456789012"
+	config.targetRole = "some-role"
+	config.sessionNameMaxLen = 100
+	buf := new(bytes.Buffer)
+
+	assert.False(t, configCheck(buf, config))
+	assert.Contains(t, buf.String(), "[fail] Mfa device of profile missing")
+	assert.Contains(t, buf.String(), "[fail] Profile missing")
+	assert.Contains(t, buf.String(), "[fail] Command line flags")
+}
+
+func TestConfigCheck_InvalidSchema(t *testing.T) {
+	path, cleanup := setupConfigCheckTest("mfaDevice:\n  default: GAHT12345678\n")
+	defer cleanup()
+	config := NewSwampConfig()
+	config.configFiles = []string{path}
+
+	results := runConfigCheck(config)
+
+	assert.Error(t, results[0].err)
+}
+
+func TestConfigCheck_RoleChain(t *testing.T) {
+	_, cleanup := setupConfigCheckTest("")
+	defer cleanup()
+	config := NewSwampConfig()
+	config.roleChainFile = "example/role-chain.yaml"
+	buf := new(bytes.Buffer)
+
+	assert.True(t, configCheck(buf, config))
+	assert.Contains(t, buf.String(), "[ok]   Assume role chain file example/role-chain.yaml")
+	assert.Contains(t, buf.String(), "[ok]   Command line flags")
+}
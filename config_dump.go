@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	REDACTED = "<redacted>"
+)
+
+// flags holding secrets, their values are never dumped
+var secretFlags = map[string]bool{
+	"external-id":       true,
+	"target-token-code": true,
+	"totp-secret":       true,
+}
+
+// key=value flags holding secrets as values, only their keys are dumped
+var secretValueFlags = map[string]bool{
+	"mfa-exec-env": true,
+}
+
+func redactValues(list stringListFlag) string {
+	redacted := make([]string, len(list))
+	for i, kv := range list {
+		redacted[i] = strings.SplitN(kv, "=", 2)[0] + "=" + REDACTED
+	}
+	return strings.Join(redacted, ",")
+}
+
+// values of all flags in effect, including the ones set by config files,
+// and settings only read from config files
+func effectiveConfig(fs *flag.FlagSet, config *SwampConfig) map[string]interface{} {
+	values := map[string]interface{}{}
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secretFlags[f.Name] && value != "" {
+			value = REDACTED
+		} else if l, ok := f.Value.(*stringListFlag); ok && secretValueFlags[f.Name] {
+			value = redactValues(*l)
+		}
+		values[f.Name] = value
+	})
+	if regions, err := config.GetProfileRegions(); err == nil && len(regions) > 0 {
+		values["region-map"] = regions
+	}
+	if len(config.roleChain) > 0 {
+		arns := make([]string, len(config.roleChain))
+		for i, hop := range config.roleChain {
+			arns[i] = hop.RoleArn
+		}
+		values["assume-role-chain"] = arns
+	}
+	return values
+}
+
+// print the effective configuration as yaml or json, sorted by flag name
+func dumpConfig(w io.Writer, fs *flag.FlagSet, config *SwampConfig, asJson, pretty bool) error {
+	values := effectiveConfig(fs, config)
+	if asJson {
+		enc := newJsonEncoder(w, pretty)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(values)
+	}
+	out, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
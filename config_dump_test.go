@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfigDumpFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("swamp", flag.ContinueOnError)
+	fs.String("region", "eu-central-1", "")
+	fs.String("totp-secret", "JBSWY3DPEHPK3PXP", "")
+	fs.String("external-id", "", "")
+	fs.Bool("renew", true, "")
+	return fs
+}
+
+func TestConfigDump_Yaml(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, dumpConfig(buf, testConfigDumpFlags(), NewSwampConfig(), false, true))
+	assert.Equal(t, `external-id: ""
+region: eu-central-1
+renew: "true"
+totp-secret: <redacted>
+`, buf.String())
+}
+
+func TestConfigDump_Json(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, dumpConfig(buf, testConfigDumpFlags(), NewSwampConfig(), true, false))
+	assert.Equal(t, `{"external-id":"","region":"eu-central-1","renew":"true","totp-secret":"<redacted>"}`+"\n", buf.String())
+}
+
+func TestConfigDump_MfaExecEnvAndConfigFileSettings(t *testing.T) {
+	config := NewSwampConfig()
+	config.profileRegions = map[string]string{"target": "eu-west-1"}
+	config.roleChain = []chainHop{{RoleArn: "arn:aws:iam::123456789012:role/jump", ExternalId: "some-external-id"}}
+	fs := flag.NewFlagSet("swamp", flag.ContinueOnError)
+	fs.Var(&config.mfaExecEnv, "mfa-exec-env", "")
+	fs.Set("mfa-exec-env", "VAULT_TOKEN=some-vault-token")
+	fs.Set("mfa-exec-env", "VAULT_ADDR=https://vault")
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, dumpConfig(buf, fs, config, false, true))
+	assert.Equal(t, `assume-role-chain:
+- arn:aws:iam::123456789012:role/jump
+mfa-exec-env: VAULT_TOKEN=<redacted>,VAULT_ADDR=<redacted>
+region-map:
+  target: eu-west-1
+`, buf.String())
+}
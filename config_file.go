@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// A configFile holds settings read from the -config yaml files.
+type configFile struct {
+	MfaDevices        map[string]string `yaml:"mfaDevices"`
+	ProtectedProfiles []string          `yaml:"protectedProfiles"`
+	RegionMap         map[string]string `yaml:"regionMap"`
+}
+
+func loadConfigFile(path string) (*configFile, error) {
+	c := &configFile{}
+	if bytes, err := ioutil.ReadFile(path); err != nil {
+		return nil, err
+	} else if err := yaml.UnmarshalStrict(bytes, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// merge settings of a later config file: its map entries win, its lists are appended
+func (c *configFile) merge(other *configFile) {
+	if len(other.MfaDevices) > 0 && c.MfaDevices == nil {
+		c.MfaDevices = map[string]string{}
+	}
+	for profile, serial := range other.MfaDevices {
+		c.MfaDevices[profile] = serial
+	}
+	if len(other.RegionMap) > 0 && c.RegionMap == nil {
+		c.RegionMap = map[string]string{}
+	}
+	for profile, region := range other.RegionMap {
+		c.RegionMap[profile] = region
+	}
+	c.ProtectedProfiles = append(c.ProtectedProfiles, other.ProtectedProfiles...)
+}
+
+// load and merge config files in the given order
+func loadConfigFiles(paths []string) (*configFile, error) {
+	merged := &configFile{}
+	for _, path := range paths {
+		c, err := loadConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		merged.merge(c)
+	}
+	return merged, nil
+}
+
+// apply settings from the config file not given on the command line
+func (config *SwampConfig) applyConfigFile(c *configFile) {
+	if config.tokenSerialNumber == "" {
+		config.tokenSerialNumber = c.MfaDevices[guessCurrentProfile(config)]
+	}
+	config.protectedProfiles = append(config.protectedProfiles, c.ProtectedProfiles...)
+	config.profileRegions = c.RegionMap
+}
+
+func (config *SwampConfig) LoadConfigFile() error {
+	if len(config.configFiles) == 0 {
+		return nil
+	}
+	c, err := loadConfigFiles(config.configFiles)
+	if err != nil {
+		return err
+	}
+	config.applyConfigFile(c)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFile_Load(t *testing.T) {
+	c, err := loadConfigFile("example/swamp.yaml")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::111111111111:mfa/BBBBBBBB", c.MfaDevices["default"])
+}
+
+func TestConfigFile_ExampleMfaDevicesAreValid(t *testing.T) {
+	c, err := loadConfigFile("example/swamp.yaml")
+
+	assert.NoError(t, err)
+	for profile, serial := range c.MfaDevices {
+		assert.NoError(t, validateMfaDevice(profile, serial))
+	}
+}
+
+func TestConfigFile_LoadMissing(t *testing.T) {
+	_, err := loadConfigFile("does-not-exists")
+
+	assert.Error(t, err)
+}
+
+func TestConfigFile_MfaDeviceForProfile(t *testing.T) {
+	config := NewSwampConfig()
+	config.profile = "team3"
+	config.configFiles = []string{"example/swamp.yaml"}
+
+	assert.NoError(t, config.LoadConfigFile())
+	assert.Equal(t, "arn:aws:iam::333333333333:mfa/BBBBBBBB", config.tokenSerialNumber)
+}
+
+func TestConfigFile_MfaDeviceFlagWins(t *testing.T) {
+	config := NewSwampConfig()
+	config.profile = "team3"
+	config.tokenSerialNumber = "some-device-id"
+	config.configFiles = []string{"example/swamp.yaml"}
+
+	assert.NoError(t, config.LoadConfigFile())
+	assert.Equal(t, "some-device-id", config.tokenSerialNumber)
+}
+
+func TestConfigFile_ProtectedProfiles(t *testing.T) {
+	config := NewSwampConfig()
+	config.protectedProfiles = []string{"from-flag"}
+	config.applyConfigFile(&configFile{ProtectedProfiles: []string{"default", "prod"}})
+
+	assert.Equal(t, stringListFlag{"from-flag", "default", "prod"}, config.protectedProfiles)
+}
+
+func TestConfigFile_Merge(t *testing.T) {
+	c := &configFile{
+		MfaDevices:        map[string]string{"default": "GAHT00000001", "team3": "GAHT00000003"},
+		ProtectedProfiles: []string{"default"},
+	}
+	c.merge(&configFile{
+		MfaDevices:        map[string]string{"team3": "GAHT00000033", "team4": "GAHT00000004"},
+		ProtectedProfiles: []string{"prod"},
+		RegionMap:         map[string]string{"team3-live": "us-east-1"},
+	})
+
+	assert.Equal(t, map[string]string{"default": "GAHT00000001", "team3": "GAHT00000033", "team4": "GAHT00000004"}, c.MfaDevices)
+	assert.Equal(t, []string{"default", "prod"}, c.ProtectedProfiles)
+	assert.Equal(t, map[string]string{"team3-live": "us-east-1"}, c.RegionMap)
+}
+
+func TestConfigFile_LoadMultiple(t *testing.T) {
+	overridePath := path.Join(os.TempDir(), "swamp-config-override.yaml")
+	defer os.Remove(overridePath)
+	ioutil.WriteFile(overridePath, []byte("mfaDevices:\n  team3: GAHT12345678\n"), 0600)
+
+	c, err := loadConfigFiles([]string{"example/swamp.yaml", overridePath})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::111111111111:mfa/BBBBBBBB", c.MfaDevices["default"])
+	assert.Equal(t, "GAHT12345678", c.MfaDevices["team3"])
+}
+
+func TestConfigFile_LoadMultipleMissing(t *testing.T) {
+	_, err := loadConfigFiles([]string{"example/swamp.yaml", "does-not-exists"})
+
+	assert.Error(t, err)
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"flag"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -11,7 +15,7 @@ func TestSwampConfig_ValidateSessionProfileOnly(t *testing.T) {
 	c.targetAccount = ""
 	c.targetRole = ""
 	c.targetProfile = ""
-	c.tokenSerialNumber = "someSerialNumber"
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
 
 	assert.NoError(t, c.Validate())
 }
@@ -62,7 +66,7 @@ func TestSwampConfig_NotDefaults(t *testing.T) {
 func TestSwampConfig_ValidateMFADeviceAndTokenCommand(t *testing.T) {
 	c := NewSwampConfig()
 	c.targetRole = "arn:aws:iam::1234567890:role/some-role"
-	c.tokenSerialNumber = "someSerialNumber"
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
 	c.mfaExec = "some command"
 
 	assert.NoError(t, c.Validate())
@@ -114,3 +118,829 @@ func TestSwampConfig_AliasesMissing(t *testing.T) {
 
 	assert.NoError(t, c.Validate())
 }
+
+func TestSwampConfig_ValidateRoleArnTemplateAndAccount(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "arn:aws:iam::{account}:role/some-role"
+
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateRoleArnTemplateWithoutAccount(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = ""
+	c.targetRole = "arn:aws:iam::{account}:role/some-role"
+
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_GetRoleArnWithTemplate(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetRole = "arn:aws:iam::{account}:role/some-role"
+	c.targetAccount = "1234567890"
+
+	arn := c.GetRoleArn()
+	assert.Equal(t, "arn:aws:iam::1234567890:role/some-role", *arn)
+}
+
+func TestSwampConfig_ValidateGovCloudRoleArn(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = ""
+	c.targetRole = "arn:aws-us-gov:iam::1234567890:role/some-role"
+
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_GetRoleArnWithAccountAndRoleInChina(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetRole = "some-role"
+	c.targetAccount = "1234567890"
+	c.region = "cn-north-1"
+
+	arn := c.GetRoleArn()
+	assert.Equal(t, "arn:aws-cn:iam::1234567890:role/some-role", *arn)
+}
+
+func TestSwampConfig_GetRoleArnWithPartitionTemplate(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetRole = "arn:{partition}:iam::{account}:role/some-role"
+	c.targetAccount = "1234567890"
+	c.region = "us-gov-west-1"
+
+	arn := c.GetRoleArn()
+	assert.Equal(t, "arn:aws-us-gov:iam::1234567890:role/some-role", *arn)
+}
+
+func TestSwampConfig_GetPartitionDefaultsToAws(t *testing.T) {
+	c := NewSwampConfig()
+	c.region = ""
+
+	assert.Equal(t, "aws", c.GetPartition())
+}
+
+func TestSwampConfig_DefaultIntermediateTokenReuseIsTrue(t *testing.T) {
+	c := NewSwampConfig()
+
+	assert.Equal(t, true, c.intermediateReuse)
+}
+
+func TestSwampConfig_ValidateChainFromProfile(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.chainFromProfile = "some-profile"
+
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateChainFromTargetProfile(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.chainFromProfile = c.targetProfile
+
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateChainFromProfileWithoutRole(t *testing.T) {
+	c := NewSwampConfig()
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	c.chainFromProfile = "some-profile"
+
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateHealthAddrWithoutRenew(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.healthAddr = ":8080"
+
+	assert.Error(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateCredentialServer(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.credentialServerAddr = "127.0.0.1:9911"
+	c.onExpiry = ON_EXPIRY_RENEW
+
+	assert.NoError(t, c.Validate())
+
+	c.exec = "bash"
+	assert.Error(t, c.Validate())
+
+	c.exec = ""
+	c.onExpiry = ON_EXPIRY_EXIT
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateOnExpiry(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+
+	for _, v := range []string{ON_EXPIRY_EXIT, ON_EXPIRY_RENEW, ON_EXPIRY_WARN} {
+		c.onExpiry = v
+		assert.NoError(t, c.Validate())
+	}
+
+	c.onExpiry = "sometimes"
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_RenewFlag(t *testing.T) {
+	c := NewSwampConfig()
+	f := &renewFlag{c}
+
+	assert.NoError(t, f.Set("true"))
+	assert.Equal(t, ON_EXPIRY_RENEW, c.onExpiry)
+	assert.Equal(t, "true", f.String())
+
+	assert.NoError(t, f.Set("false"))
+	assert.Equal(t, ON_EXPIRY_EXIT, c.onExpiry)
+	assert.Error(t, f.Set("maybe"))
+}
+
+func TestSwampConfig_ValidateVaultPath(t *testing.T) {
+	os.Setenv("VAULT_ADDR", "http://vault:8200")
+	os.Setenv("VAULT_TOKEN", "some-vault-token")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.vaultPath = "secret/aws/prod"
+
+	assert.NoError(t, c.Validate())
+
+	c.vaultKvVersion = 3
+	assert.Error(t, c.Validate())
+
+	c.vaultKvVersion = 1
+	os.Unsetenv("VAULT_TOKEN")
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateQuietIfValidWithRenew(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.quietIfValid = true
+
+	assert.NoError(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateExecEnv(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.execEnv = EXEC_ENV_CREDENTIALS
+
+	assert.NoError(t, c.Validate())
+
+	c.quietIfValid = true
+	assert.Error(t, c.Validate())
+
+	c.quietIfValid = false
+	c.execEnv = "file"
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateTotpSecret(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetRole = "arn:aws:iam::1234567890:role/some-role"
+	c.totpSecret = "GEZDGNBVGY3TQOJQ"
+
+	assert.Error(t, c.Validate())
+
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	assert.NoError(t, c.Validate())
+
+	c.mfaExec = "some command"
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateRenewIfUsed(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.renewIfUsed = true
+
+	assert.Error(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateCredentialProcess(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.credentialProcess = true
+
+	assert.NoError(t, c.Validate())
+
+	c.credentialProcessSkew = 2 * time.Hour
+	assert.Error(t, c.Validate())
+
+	c.credentialProcessSkew = time.Minute
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_GetRegionSet(t *testing.T) {
+	c := NewSwampConfig()
+	assert.Nil(t, c.GetRegionSet())
+
+	c.regionSet = "us-east-1, eu-west-1,"
+	assert.Equal(t, []string{"us-east-1", "eu-west-1"}, c.GetRegionSet())
+	assert.Equal(t, "swamp-us-east-1", regionalProfileName(c.targetProfile, "us-east-1"))
+}
+
+func TestSwampConfig_ValidateRegionSet(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.regionSet = "us-east-1,eu-west1"
+
+	assert.Error(t, c.Validate())
+
+	c.regionSet = "us-east-1,eu-west-1"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateTemplateFile(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.templateFile = "example/netrc.tmpl"
+
+	assert.Error(t, c.Validate())
+
+	c.templateOut = "some-file"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateTargetMfaDevice(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetMfaDevice = "arn:aws:iam::123456789012:mfa/some-user"
+
+	assert.Error(t, c.Validate())
+
+	c.targetRole = "some-role"
+	c.mfaExec = "some command"
+	assert.NoError(t, c.Validate())
+
+	c.targetTokenCode = "123456"
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.Error(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_EXIT
+	c.targetMfaDevice = ""
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateRefreshJitter(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.refreshJitter = time.Minute
+
+	assert.Error(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.NoError(t, c.Validate())
+
+	c.refreshJitter = time.Hour
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateRenewInBackground(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.renewInBackground = true
+	c.backgroundLog = "/some/dir/background.log"
+
+	assert.Error(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.NoError(t, c.Validate())
+
+	c.healthAddr = ":8080"
+	assert.Error(t, c.Validate())
+
+	c.healthAddr = ""
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/user"
+	assert.EqualError(t, c.Validate(), "Renew in background can't ask for mfa tokens, requires -mfa-exec, -totp-secret or -totp-secret-file")
+
+	c.mfaExec = "pass otp aws"
+	c.backgroundLog = ""
+	assert.EqualError(t, c.Validate(), "Renew in background requires -background-log")
+}
+
+func TestSwampConfig_DefaultBackgroundLog(t *testing.T) {
+	os.Setenv("XDG_CACHE_HOME", "/some/cache")
+	defer os.Unsetenv("XDG_CACHE_HOME")
+
+	assert.Equal(t, "/some/cache/swamp/background.log", defaultBackgroundLog())
+}
+
+func TestSwampConfig_ValidatePrintEnvExport(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.printEnvExport = true
+	c.envPrefix = "PROD_"
+
+	assert.NoError(t, c.Validate())
+
+	c.shell = "csh"
+	assert.Error(t, c.Validate())
+
+	c.shell = SHELL_FISH
+	c.credentialProcess = true
+	assert.Error(t, c.Validate())
+
+	c.credentialProcess = false
+	c.printExpiry = PRINT_EXPIRY_EPOCH
+	assert.EqualError(t, c.Validate(), "Print env export and print expiry are mutual exclusive")
+
+	c.printExpiry = ""
+	c.exec = "aws s3 ls"
+	assert.EqualError(t, c.Validate(), "Print env export and exec are mutual exclusive")
+
+	c.exec = ""
+	c.printEnvExport = false
+	c.noEnvUnset = true
+	assert.EqualError(t, c.Validate(), "No env unset requires -print-env-export")
+}
+
+func TestSwampConfig_ValidateMfaDevice(t *testing.T) {
+	assert.NoError(t, validateMfaDevice("mfa-device", "arn:aws:iam::123456789012:mfa/some.user@example.com"))
+	assert.NoError(t, validateMfaDevice("mfa-device", "arn:aws-us-gov:iam::123456789012:mfa/some-user"))
+	assert.NoError(t, validateMfaDevice("mfa-device", "GAHT12345678"))
+	assert.Error(t, validateMfaDevice("mfa-device", "+491701234567"))
+	assert.Error(t, validateMfaDevice("mfa-device", "my-phone"))
+	assert.Error(t, validateMfaDevice("mfa-device", "arn:aws:iam::123456789012:user/some-user"))
+	assert.Error(t, validateMfaDevice("mfa-device", "arn:aws:iam::1234:mfa/some-user"))
+}
+
+func TestSwampConfig_ValidateProfileTemplate(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "123456789012"
+	c.targetRole = "some-role"
+	c.profileTemplate = "{account}-{role}"
+
+	assert.NoError(t, c.Validate())
+
+	c.profilePerAccount = true
+	assert.Error(t, c.Validate())
+
+	c.profilePerAccount = false
+	c.profileTemplate = "{acount}-{role}"
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateConfigureBase(t *testing.T) {
+	c := NewSwampConfig()
+	c.configureBase = true
+	c.profile = "some-base-profile"
+
+	assert.NoError(t, c.Validate())
+
+	c.profile = c.intermediateProfile
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidatePrintExpiry(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.printExpiry = PRINT_EXPIRY_EPOCH
+
+	assert.NoError(t, c.Validate())
+
+	c.printExpiry = "unix"
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateCrossPartitionRoleArn(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetRole = "arn:aws-us-gov:iam::1234567890:role/some-role"
+	c.region = "eu-central-1"
+
+	assert.Error(t, c.Validate())
+
+	c.region = "us-gov-west-1"
+	assert.NoError(t, c.Validate())
+
+	c.assumeRoleRegion = "cn-north-1"
+	assert.Error(t, c.Validate())
+
+	c.assumeRoleRegion = ""
+	c.targetRole = "arn:{partition}:iam::{account}:role/some-role"
+	c.targetAccount = "1234567890"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateMfaCacheScope(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.mfaCacheScope = MFA_CACHE_SCOPE_AWS_CLI
+
+	assert.Error(t, c.Validate())
+
+	c.targetMfaDevice = "arn:aws:iam::123456789012:mfa/some-user"
+	assert.NoError(t, c.Validate())
+
+	c.mfaCacheScope = "global"
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateExternalIdFromEnv(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.externalId = "env:SWAMP_TEST_EXTERNAL_ID"
+
+	assert.Error(t, c.Validate())
+
+	os.Setenv("SWAMP_TEST_EXTERNAL_ID", "some-secret-external-id")
+	defer os.Clearenv()
+	assert.NoError(t, c.Validate())
+	assert.Equal(t, "some-secret-external-id", c.externalId)
+
+	c.externalId = "some external id"
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateExecKeepEnv(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.execKeepEnv = true
+
+	assert.NoError(t, c.Validate())
+
+	c.execEnv = EXEC_ENV_BOTH
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateSessionNameMaxLen(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+
+	assert.NoError(t, c.Validate())
+
+	c.sessionNameMaxLen = 65
+	assert.Error(t, c.Validate())
+
+	c.sessionNameMaxLen = 1
+	assert.Error(t, c.Validate())
+
+	c.sessionNameMaxLen = 32
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateConfigCheck(t *testing.T) {
+	c := NewSwampConfig()
+	c.configCheck = true
+
+	assert.Error(t, c.Validate())
+
+	c.configFiles = []string{"example/swamp.yaml"}
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateWarnBeforeExpiry(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.warnBeforeExpiry = 5 * time.Minute
+
+	assert.Error(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.NoError(t, c.Validate())
+
+	c.warnBeforeExpiry = -time.Minute
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_TargetDurationFlag(t *testing.T) {
+	c := NewSwampConfig()
+	f := &targetDurationFlag{c}
+	assert.Equal(t, "3600", f.String())
+
+	assert.NoError(t, f.Set("max"))
+	assert.True(t, c.targetDurationMax)
+	assert.Equal(t, "max", f.String())
+
+	assert.NoError(t, f.Set("7200"))
+	assert.False(t, c.targetDurationMax)
+	assert.Equal(t, int64(7200), c.targetDuration)
+	assert.Equal(t, "7200", f.String())
+
+	assert.Error(t, f.Set("long"))
+}
+
+func TestSwampConfig_ValidateReason(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.reason = "TICKET-123"
+
+	assert.NoError(t, c.Validate())
+
+	c.reason = "TICKET 123"
+	assert.Error(t, c.Validate())
+
+	c.reason = strings.Repeat("a", 62)
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateRenewLimits(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.renewFor = 8 * time.Hour
+
+	assert.Error(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	c.renewMaxIterations = 10
+	assert.NoError(t, c.Validate())
+
+	c.renewMaxIterations = -1
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateBackupKeep(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.credentialsBackup = true
+
+	assert.NoError(t, c.Validate())
+
+	c.backupKeep = 0
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateAliasCheck(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.aliasCheck = "example/bash_aliases.sh"
+
+	assert.Error(t, c.Validate())
+
+	c.aliasConfig = "example/config.yaml"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateMfaExecShell(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.mfaExecShell = " "
+
+	assert.Error(t, c.Validate())
+
+	c.mfaExecShell = SHELL_NONE
+	assert.NoError(t, c.Validate())
+
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/user"
+	c.mfaExec = " "
+	assert.EqualError(t, c.Validate(), `Invalid value for mfa-exec: " "`)
+
+	c.mfaExec = "pass otp aws"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateStrictRegion(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.strictRegion = true
+
+	assert.EqualError(t, c.Validate(), "Strict region requires -region, AWS_REGION or AWS_DEFAULT_REGION")
+
+	os.Setenv("AWS_DEFAULT_REGION", "eu-central-1")
+	assert.NoError(t, c.Validate())
+
+	os.Clearenv()
+	c.region = "eu-central-1"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateJsonFd(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.jsonFd = 4
+
+	assert.NoError(t, c.Validate())
+
+	c.jsonFd = 1
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidatePolicyFromStdin(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.policyFile = "-"
+
+	assert.NoError(t, c.Validate())
+
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	assert.Error(t, c.Validate())
+
+	c.mfaExec = "echo 123456"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateProfileAlias(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.profileAliases = []string{"picky-tool"}
+
+	assert.NoError(t, c.Validate())
+
+	c.profileAliases = []string{"picky-tool", c.targetProfile}
+	assert.Error(t, c.Validate())
+
+	c.profileAliases = []string{"picky-tool"}
+	c.credentialServerAddr = "127.0.0.1:9911"
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.Error(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateBaseAccountId(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.baseAccountId = "123456789012"
+
+	assert.NoError(t, c.Validate())
+
+	c.baseAccountId = "1234"
+	assert.EqualError(t, c.Validate(), "Invalid value for base-account-id: 1234")
+}
+
+func TestSwampConfig_ValidateMfaExecEnv(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	c.mfaExecEnv = []string{"GPG_TTY=/dev/pts/0"}
+
+	assert.EqualError(t, c.Validate(), "Mfa exec env requires -mfa-exec")
+
+	c.mfaExec = "pass otp aws"
+	assert.NoError(t, c.Validate())
+
+	c.mfaExecEnv = []string{"GPG_TTY"}
+	assert.EqualError(t, c.Validate(), "Invalid value for mfa-exec-env: GPG_TTY, expected key=value")
+}
+
+func TestSwampConfig_ValidateOpVault(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.opVault = "Platform"
+
+	assert.EqualError(t, c.Validate(), "Op vault requires -op-item")
+
+	c.opItem = "aws-prod"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_ValidateAccountFromCaller(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetRole = "some-role"
+	c.accountFromCaller = true
+
+	assert.NoError(t, c.Validate())
+
+	c.targetAccount = "123456789012"
+	assert.EqualError(t, c.Validate(), "Account from caller and account are mutual exclusive")
+
+	c.targetAccount = ""
+	c.targetRole = "arn:aws:iam::123456789012:role/some-role"
+	assert.EqualError(t, c.Validate(), "Account from caller requires a target role name or arn template")
+
+	c.targetRole = "arn:aws:iam::{account}:role/some-role"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_OptionalBoolFlag(t *testing.T) {
+	var f optionalBoolFlag
+	fs := flag.NewFlagSet("swamp", flag.ContinueOnError)
+	fs.Var(&f, "json-pretty", "")
+
+	assert.NoError(t, fs.Parse(nil))
+	assert.True(t, f.Or(true))
+	assert.False(t, f.Or(false))
+
+	assert.NoError(t, fs.Parse([]string{"-json-pretty"}))
+	assert.True(t, f.Or(false))
+
+	assert.NoError(t, fs.Parse([]string{"-json-pretty=false"}))
+	assert.False(t, f.Or(true))
+}
+
+func TestSwampConfig_ValidateNothingToDo(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+
+	assert.EqualError(t, c.Validate(), "Nothing to do, missing -target-role or -assume-role-chain-file to assume a role, -mfa-device for a session token only or -federation-name for a federation token")
+
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	assert.NoError(t, c.Validate())
+}
+
+func TestSwampConfig_DeriveIntermediateProfile(t *testing.T) {
+	c := NewSwampConfig()
+	c.profile = "team3"
+	c.DeriveIntermediateProfile(flag.NewFlagSet("swamp", flag.ContinueOnError))
+	assert.Equal(t, "session-token", c.intermediateProfile)
+
+	c.intermediateProfileAuto = true
+	c.DeriveIntermediateProfile(flag.NewFlagSet("swamp", flag.ContinueOnError))
+	assert.Equal(t, "team3-session", c.intermediateProfile)
+
+	c.intermediateSuffix = "-mfa"
+	c.DeriveIntermediateProfile(flag.NewFlagSet("swamp", flag.ContinueOnError))
+	assert.Equal(t, "team3-mfa", c.intermediateProfile)
+}
+
+func TestSwampConfig_ValidateIntermediateProfileDiffersFromBase(t *testing.T) {
+	c := NewSwampConfig()
+	c.profile = "team3"
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	c.intermediateProfileAuto = true
+	c.intermediateSuffix = ""
+	c.DeriveIntermediateProfile(flag.NewFlagSet("swamp", flag.ContinueOnError))
+
+	assert.EqualError(t, c.Validate(), "Intermediate profile must differ from base profile team3")
+}
+
+func TestSwampConfig_ValidateIntermediateProfileAutoWithIntermediateProfile(t *testing.T) {
+	c := NewSwampConfig()
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	c.intermediateProfileAuto = true
+	fs := flag.NewFlagSet("swamp", flag.ContinueOnError)
+	fs.StringVar(&c.intermediateProfile, "intermediate-profile", c.intermediateProfile, "")
+	fs.Parse([]string{"-intermediate-profile", "my-session"})
+	c.DeriveIntermediateProfile(fs)
+
+	assert.Equal(t, "my-session", c.intermediateProfile)
+	assert.EqualError(t, c.Validate(), "Intermediate profile auto and intermediate profile are mutual exclusive")
+}
+
+func TestSwampConfig_ValidateOnce(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.once = true
+
+	assert.NoError(t, c.Validate())
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.EqualError(t, c.Validate(), "Once and on-expiry=renew are mutual exclusive")
+
+	c.onExpiry = ON_EXPIRY_WARN
+	assert.EqualError(t, c.Validate(), "Once and on-expiry=warn are mutual exclusive")
+}
+
+func TestSwampConfig_ValidateCaBundleWithNoVerifySsl(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.noVerifySsl = true
+
+	assert.NoError(t, c.Validate())
+
+	c.caBundle = "ca.pem"
+	assert.EqualError(t, c.Validate(), "Ca bundle and no verify ssl are mutual exclusive")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A configWatcher re-reads -config, -assume-role-chain-file and -policy files while renewing.
+// The files are applied on top of the flags given on start, an invalid reload keeps the previous config.
+type configWatcher struct {
+	flags  SwampConfig
+	loaded SwampConfig
+}
+
+func newConfigWatcher(flags SwampConfig) *configWatcher {
+	return &configWatcher{flags: flags}
+}
+
+// remember the config loaded on start to report changes of the first reload against it
+func (w *configWatcher) Loaded(config *SwampConfig) {
+	w.loaded = *config
+}
+
+// re-read the config files into config and return what changed
+func (w *configWatcher) Reload(config *SwampConfig) ([]string, error) {
+	next := w.flags
+	next.protectedProfiles = append(stringListFlag(nil), w.flags.protectedProfiles...)
+	// keep state established on start, like the region detected from instance metadata
+	next.tokenCodes = config.tokenCodes
+	next.region = config.region
+
+	if err := next.LoadConfigFile(); err != nil {
+		return nil, fmt.Errorf("Error reading config file: %s", err)
+	}
+	if err := next.LoadRoleChain(); err != nil {
+		return nil, fmt.Errorf("Error reading assume role chain file: %s", err)
+	}
+	if next.policyFile == POLICY_STDIN {
+		// stdin is read only once on start
+		next.policy = w.loaded.policy
+	} else if err := next.LoadSessionPolicy(); err != nil {
+		return nil, fmt.Errorf("Error reading session policy: %s", err)
+	}
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	changes := configChanges(&w.loaded, &next)
+	w.loaded = next
+	*config = next
+	return changes, nil
+}
+
+func roleChainArns(hops []chainHop) string {
+	var arns []string
+	for _, hop := range hops {
+		arns = append(arns, hop.RoleArn)
+	}
+	return strings.Join(arns, " -> ")
+}
+
+// settings taken from config files that differ between prev and next
+func configChanges(prev, next *SwampConfig) []string {
+	var changes []string
+	for _, c := range []struct{ name, prev, next string }{
+		{"mfa-device", prev.tokenSerialNumber, next.tokenSerialNumber},
+		{"protected-profile", prev.protectedProfiles.String(), next.protectedProfiles.String()},
+		{"assume-role-chain", roleChainArns(prev.roleChain), roleChainArns(next.roleChain)},
+		{"target-role", prev.targetRole, next.targetRole},
+		{"target-duration", fmt.Sprint(prev.targetDuration), fmt.Sprint(next.targetDuration)},
+		{"region-map", fmt.Sprint(prev.profileRegions), fmt.Sprint(next.profileRegions)},
+	} {
+		if c.prev != c.next {
+			changes = append(changes, fmt.Sprintf("%s changed from %q to %q", c.name, c.prev, c.next))
+		}
+	}
+	if prev.policy != next.policy {
+		changes = append(changes, "policy changed")
+	}
+	return changes
+}
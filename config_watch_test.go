@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigWatch_Reload(t *testing.T) {
+	chainFile := path.Join(os.TempDir(), "swamp-watch-test.yaml")
+	defer os.Remove(chainFile)
+	ioutil.WriteFile(chainFile, []byte("hops:\n  - roleArn: arn:aws:iam::111111111111:role/deploy\n"), 0644)
+
+	config := NewSwampConfig()
+	config.roleChainFile = chainFile
+	config.onExpiry = ON_EXPIRY_RENEW
+	config.watchConfig = true
+	watcher := newConfigWatcher(*config)
+	assert.NoError(t, config.LoadRoleChain())
+	assert.NoError(t, config.Validate())
+	watcher.Loaded(config)
+
+	changes, err := watcher.Reload(config)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	ioutil.WriteFile(chainFile, []byte("hops:\n  - roleArn: arn:aws:iam::111111111111:role/deploy\n    duration: 7200\n"), 0644)
+	changes, err = watcher.Reload(config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`target-duration changed from "3600" to "7200"`}, changes)
+	assert.Equal(t, int64(7200), config.targetDuration)
+
+	ioutil.WriteFile(chainFile, []byte("hops:\n  - roleArn: some-role\n"), 0644)
+	_, err = watcher.Reload(config)
+	assert.Error(t, err)
+	assert.Equal(t, "arn:aws:iam::111111111111:role/deploy", config.targetRole)
+	assert.Equal(t, int64(7200), config.targetDuration)
+}
+
+func TestConfigWatch_ReloadProtectsProfiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "swamp-watch-test")
+	defer os.RemoveAll(dir)
+	configFile := path.Join(dir, "swamp.yaml")
+	ioutil.WriteFile(configFile, []byte("protectedProfiles: []\n"), 0644)
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", path.Join(dir, "credentials"))
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+
+	config := NewSwampConfig()
+	config.configFiles = []string{configFile}
+	config.targetAccount = "123456789012"
+	config.targetRole = "admin"
+	config.onExpiry = ON_EXPIRY_RENEW
+	config.watchConfig = true
+	watcher := newConfigWatcher(*config)
+	assert.NoError(t, config.LoadConfigFile())
+	assert.NoError(t, config.Validate())
+	watcher.Loaded(config)
+	pw := initProfileWriter(config)
+	assert.NoError(t, pw.WriteProfile(testCredentials(), aws.String("prod"), nil))
+
+	ioutil.WriteFile(configFile, []byte("protectedProfiles:\n  - prod\n"), 0644)
+	_, err := watcher.Reload(config)
+	assert.NoError(t, err)
+	configureProfileWriter(pw, config)
+
+	assert.EqualError(t, pw.WriteProfile(testCredentials(), aws.String("prod"), nil), "Refusing to overwrite protected profile prod, use -force to overwrite it anyway")
+}
+
+func TestConfigWatch_Validate(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.watchConfig = true
+
+	assert.EqualError(t, c.Validate(), "Watch config requires -renew")
+
+	c.onExpiry = ON_EXPIRY_RENEW
+	assert.EqualError(t, c.Validate(), "Watch config requires -config, -assume-role-chain-file or -policy")
+
+	c.configFiles = []string{"example/swamp.yaml"}
+	assert.NoError(t, c.Validate())
+}
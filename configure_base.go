@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var accessKeyIdPattern = regexp.MustCompile(`^[A-Z0-9]{16,128}$`)
+
+// static keys from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or asked for on r
+func readStaticKeys(r io.Reader, prompt io.Writer) (string, string, error) {
+	accessKeyId := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKeyId == "" || secretAccessKey == "" {
+		reader := bufio.NewReader(r)
+		var err error
+		fmt.Fprint(prompt, "AWS access key id: ")
+		if accessKeyId, err = reader.ReadString('\n'); err != nil {
+			return "", "", fmt.Errorf("Error reading access key id: %s", err)
+		}
+		fmt.Fprint(prompt, "AWS secret access key: ")
+		if secretAccessKey, err = reader.ReadString('\n'); err != nil && err != io.EOF {
+			return "", "", fmt.Errorf("Error reading secret access key: %s", err)
+		}
+	}
+	accessKeyId = strings.TrimSpace(accessKeyId)
+	secretAccessKey = strings.TrimSpace(secretAccessKey)
+	if !accessKeyIdPattern.MatchString(accessKeyId) {
+		return "", "", fmt.Errorf("Invalid access key id: %s", accessKeyId)
+	}
+	if secretAccessKey == "" {
+		return "", "", errors.New("Secret access key must not be empty")
+	}
+	return accessKeyId, secretAccessKey, nil
+}
+
+// write static keys read from environment or stdin into the base profile
+func configureBase(config *SwampConfig, pw *ProfileWriter) error {
+	accessKeyId, secretAccessKey, err := readStaticKeys(os.Stdin, os.Stderr)
+	if err != nil {
+		return err
+	}
+	profile := guessCurrentProfile(config)
+	return pw.WriteStaticProfile(accessKeyId, secretAccessKey, &profile)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	CREDENTIAL_PROCESS_CACHE_MARGIN = 5 * time.Minute
+)
+
+// credentials in the format expected from a credential_process
+type processCredentials struct {
+	Version         int
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      string
+}
+
+// expiration is reported skew earlier, so sdks refresh before sts considers the credentials expired
+func newProcessCredentials(cred *sts.Credentials, skew time.Duration) *processCredentials {
+	return &processCredentials{
+		Version:         1,
+		AccessKeyId:     *cred.AccessKeyId,
+		SecretAccessKey: *cred.SecretAccessKey,
+		SessionToken:    *cred.SessionToken,
+		Expiration:      cred.Expiration.Add(-skew).UTC().Format(time.RFC3339),
+	}
+}
+
+// A credentialProcessCacheKey holds everything going into the assume-role requests,
+// so invocations scoped down by policies or tags never share credentials with broader ones.
+type credentialProcessCacheKey struct {
+	RoleArn               string
+	Profile               string
+	MfaDevice             string
+	Region                string
+	TargetMfaDevice       string
+	AccountFromCaller     bool
+	PolicyArns            []string
+	Policy                string
+	Tags                  map[string]string
+	ExternalId            string
+	SourceIdentityFromSso bool
+	Duration              int64
+	DurationMax           bool
+	AutoClampDuration     bool
+	Reason                string
+	SessionNameMaxLen     int
+	SessionNameHash       bool
+	RoleChain             []chainHop
+}
+
+// one cache file per assume-role request and base identity
+func getCredentialProcessCachePath(config *SwampConfig) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	tags, err := config.GetSessionTags()
+	if err != nil {
+		return "", err
+	}
+	key, err := json.Marshal(credentialProcessCacheKey{
+		RoleArn:               *config.GetRoleArn(),
+		Profile:               config.profile,
+		MfaDevice:             config.tokenSerialNumber,
+		Region:                config.region,
+		TargetMfaDevice:       config.targetMfaDevice,
+		AccountFromCaller:     config.accountFromCaller,
+		PolicyArns:            config.policyArns,
+		Policy:                config.policy,
+		Tags:                  tags,
+		ExternalId:            config.externalId,
+		SourceIdentityFromSso: config.sourceIdentityFromSso,
+		Duration:              config.targetDuration,
+		DurationMax:           config.targetDurationMax,
+		AutoClampDuration:     config.autoClampDuration,
+		Reason:                config.reason,
+		SessionNameMaxLen:     config.sessionNameMaxLen,
+		SessionNameHash:       config.sessionNameHash,
+		RoleChain:             config.roleChain,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(key)
+	return filepath.Join(dir, "swamp", "credential-process", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// cached credentials still valid for a while, nil otherwise
+func readCachedProcessCredentials(path string, now time.Time) *processCredentials {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	pc := &processCredentials{}
+	if err := json.Unmarshal(b, pc); err != nil {
+		return nil
+	}
+	expiration, err := time.Parse(time.RFC3339, pc.Expiration)
+	if err != nil || expiration.Before(now.Add(CREDENTIAL_PROCESS_CACHE_MARGIN)) {
+		return nil
+	}
+	return pc
+}
+
+func writeCachedProcessCredentials(path string, pc *processCredentials) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(pc)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// print cached or freshly assumed target credentials to w
+func runCredentialProcess(w io.Writer, config *SwampConfig) {
+	cachePath, err := getCredentialProcessCachePath(config)
+	if err != nil {
+		die("Error finding credential cache", err)
+	}
+	pc := readCachedProcessCredentials(cachePath, clock.Now())
+	if pc == nil {
+		resolveRegion(config, detectEc2Region)
+		verifyBaseAccount(config)
+		if config.tokenSerialNumber != "" {
+			ensureSessionTokenProfile(config, initProfileWriter(config))
+		}
+		options := getAssumeRoleSessionOptions(config)
+		sess := session.Must(session.NewSessionWithOptions(options))
+		pc = newProcessCredentials(assumeTargetRole(config, sess, options.Profile), config.credentialProcessSkew)
+		if err := writeCachedProcessCredentials(cachePath, pc); err != nil {
+			printer.Printf("Unable to write credential cache %s: %s\n", cachePath, err)
+		}
+	}
+	if err := newJsonEncoder(w, config.jsonPretty.Or(false)).Encode(pc); err != nil {
+		die("Error writing credentials", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialProcess_NewProcessCredentials(t *testing.T) {
+	creds := testCredentials()
+	creds.SetExpiration(time.Date(2017, 7, 6, 8, 31, 10, 0, time.UTC))
+
+	assert.Equal(t, &processCredentials{
+		Version:         1,
+		AccessKeyId:     "some-access-key",
+		SecretAccessKey: "some-secret-access-key",
+		SessionToken:    "some-session-token",
+		Expiration:      "2017-07-06T08:31:10Z",
+	}, newProcessCredentials(creds, 0))
+
+	assert.Equal(t, "2017-07-06T08:30:10Z", newProcessCredentials(creds, time.Minute).Expiration)
+}
+
+func TestCredentialProcess_Cache(t *testing.T) {
+	cachePath := path.Join(os.TempDir(), "swamp-test-cache", "credential-process.json")
+	defer os.RemoveAll(path.Dir(cachePath))
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	pc := &processCredentials{Version: 1, AccessKeyId: "some-access-key", Expiration: "2017-07-06T09:00:00Z"}
+
+	assert.Nil(t, readCachedProcessCredentials(cachePath, now))
+	assert.NoError(t, writeCachedProcessCredentials(cachePath, pc))
+
+	assert.Equal(t, pc, readCachedProcessCredentials(cachePath, now))
+	assert.Nil(t, readCachedProcessCredentials(cachePath, now.Add(56*time.Minute)))
+}
+
+func TestCredentialProcess_CachePathPerAssumeRoleRequest(t *testing.T) {
+	os.Setenv("XDG_CACHE_HOME", path.Join(os.TempDir(), "swamp-test-cache"))
+	defer os.Unsetenv("XDG_CACHE_HOME")
+	config := NewSwampConfig()
+	config.targetRole = "arn:aws:iam::123456789012:role/some-role"
+	unrestricted, err := getCredentialProcessCachePath(config)
+	assert.NoError(t, err)
+
+	config.policyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+	restricted, _ := getCredentialProcessCachePath(config)
+	assert.NotEqual(t, unrestricted, restricted)
+
+	config.policyArns = nil
+	config.policy = `{"Version":"2012-10-17","Statement":[]}`
+	inline, _ := getCredentialProcessCachePath(config)
+	assert.NotEqual(t, unrestricted, inline)
+
+	config.policy = ""
+	config.roleChain = []chainHop{{RoleArn: "arn:aws:iam::123456789012:role/jump"}, {RoleArn: "arn:aws:iam::123456789012:role/some-role"}}
+	chained, _ := getCredentialProcessCachePath(config)
+	assert.NotEqual(t, unrestricted, chained)
+
+	config.roleChain = nil
+	again, _ := getCredentialProcessCachePath(config)
+	assert.Equal(t, unrestricted, again)
+}
+
+func TestCredentialProcess_PrintsCachedCredentials(t *testing.T) {
+	cacheDir := path.Join(os.TempDir(), "swamp-test-cache")
+	os.Setenv("XDG_CACHE_HOME", cacheDir)
+	defer os.Unsetenv("XDG_CACHE_HOME")
+	defer os.RemoveAll(cacheDir)
+
+	config := NewSwampConfig()
+	config.targetRole = "arn:aws:iam::1234567890:role/some-role"
+	cachePath, err := getCredentialProcessCachePath(config)
+	assert.NoError(t, err)
+	expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	writeCachedProcessCredentials(cachePath, &processCredentials{Version: 1, AccessKeyId: "some-access-key", Expiration: expiration})
+
+	buf := new(bytes.Buffer)
+	runCredentialProcess(buf, config)
+
+	assert.Equal(t, `{"Version":1,"AccessKeyId":"some-access-key","SecretAccessKey":"","SessionToken":"","Expiration":"`+expiration+`"}`+"\n", buf.String())
+}
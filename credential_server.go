@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	CREDENTIAL_SERVER_PATH = "/credentials"
+)
+
+type containerCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	Token           string
+	Expiration      string
+}
+
+// A credentialServer serves the latest assumed credentials in the
+// ecs container credentials format.
+type credentialServer struct {
+	mu     sync.Mutex
+	cred   *sts.Credentials
+	token  string
+	served bool // were the current credentials requested yet?
+}
+
+func newCredentialServer() (*credentialServer, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &credentialServer{token: hex.EncodeToString(b)}, nil
+}
+
+func (cs *credentialServer) SetCredentials(cred *sts.Credentials) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.cred = cred
+	cs.served = false
+}
+
+// Used reports whether the current credentials were requested since they were set.
+func (cs *credentialServer) Used() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.served
+}
+
+func (cs *credentialServer) markServed() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.served = true
+}
+
+func (cs *credentialServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(CREDENTIAL_SERVER_PATH, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != cs.token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		cs.mu.Lock()
+		cred := cs.cred
+		cs.mu.Unlock()
+		if cred == nil {
+			http.Error(w, "no credentials yet", http.StatusServiceUnavailable)
+			return
+		}
+		cs.markServed()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(containerCredentials{
+			AccessKeyId:     *cred.AccessKeyId,
+			SecretAccessKey: *cred.SecretAccessKey,
+			Token:           *cred.SessionToken,
+			Expiration:      cred.Expiration.UTC().Format(time.RFC3339),
+		})
+	})
+	return mux
+}
+
+func (cs *credentialServer) Serve(addr string) {
+	printer.Printf("Serving credentials, point your application to them with:\n")
+	printer.Printf("export AWS_CONTAINER_CREDENTIALS_FULL_URI=http://%s%s\n", addr, CREDENTIAL_SERVER_PATH)
+	printer.Printf("export AWS_CONTAINER_AUTHORIZATION_TOKEN=%s\n", cs.token)
+	go func() {
+		if err := http.ListenAndServe(addr, cs.Handler()); err != nil {
+			die("Error serving credentials", err)
+		}
+	}()
+}
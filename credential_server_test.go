@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialServer_ServeCredentials(t *testing.T) {
+	cs, err := newCredentialServer()
+	assert.NoError(t, err)
+	creds := testCredentials()
+	creds.SetExpiration(time.Date(2017, 7, 6, 8, 31, 10, 0, time.UTC))
+	cs.SetCredentials(creds)
+
+	req := httptest.NewRequest("GET", CREDENTIAL_SERVER_PATH, nil)
+	req.Header.Set("Authorization", cs.token)
+	rec := httptest.NewRecorder()
+	cs.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var c containerCredentials
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &c))
+	assert.Equal(t, containerCredentials{
+		AccessKeyId:     "some-access-key",
+		SecretAccessKey: "some-secret-access-key",
+		Token:           "some-session-token",
+		Expiration:      "2017-07-06T08:31:10Z",
+	}, c)
+}
+
+func TestCredentialServer_Unauthorized(t *testing.T) {
+	cs, _ := newCredentialServer()
+	rec := httptest.NewRecorder()
+
+	cs.Handler().ServeHTTP(rec, httptest.NewRequest("GET", CREDENTIAL_SERVER_PATH, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestCredentialServer_NoCredentialsYet(t *testing.T) {
+	cs, _ := newCredentialServer()
+	req := httptest.NewRequest("GET", CREDENTIAL_SERVER_PATH, nil)
+	req.Header.Set("Authorization", cs.token)
+	rec := httptest.NewRecorder()
+
+	cs.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
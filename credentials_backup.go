@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	BACKUP_SUFFIX      = ".swamp-backup-"
+	BACKUP_TIME_LAYOUT = "20060102T150405.000000000Z"
+)
+
+// copy the file at path to a timestamped backup next to it and keep only the newest keep backups,
+// a missing file is not backed up
+func backupFile(path string, now time.Time, keep int) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	backupPath := path + BACKUP_SUFFIX + now.UTC().Format(BACKUP_TIME_LAYOUT)
+	if err := ioutil.WriteFile(backupPath, b, 0600); err != nil {
+		return err
+	}
+	return pruneBackups(path, keep)
+}
+
+func pruneBackups(path string, keep int) error {
+	backups, err := filepath.Glob(path + BACKUP_SUFFIX + "*")
+	if err != nil {
+		return err
+	}
+	// the timestamp layout sorts chronologically
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
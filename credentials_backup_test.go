@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsBackup_KeepsNewest(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "swamp-backup-test")
+	defer os.RemoveAll(dir)
+	credPath := path.Join(dir, "credentials")
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		ioutil.WriteFile(credPath, []byte{byte('a' + i)}, 0600)
+		assert.NoError(t, backupFile(credPath, now.Add(time.Duration(i)*time.Second), 2))
+	}
+
+	backups, _ := filepath.Glob(credPath + BACKUP_SUFFIX + "*")
+	assert.Equal(t, []string{
+		credPath + ".swamp-backup-20170706T080002.000000000Z",
+		credPath + ".swamp-backup-20170706T080003.000000000Z",
+	}, backups)
+	b, _ := ioutil.ReadFile(backups[1])
+	assert.Equal(t, "d", string(b))
+}
+
+func TestCredentialsBackup_MissingFile(t *testing.T) {
+	credPath := path.Join(os.TempDir(), "swamp-backup-missing")
+
+	assert.NoError(t, backupFile(credPath, time.Now(), 5))
+
+	backups, _ := filepath.Glob(credPath + BACKUP_SUFFIX + "*")
+	assert.Empty(t, backups)
+}
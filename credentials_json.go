@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	CREDENTIALS_FORMAT_INI  = "ini"
+	CREDENTIALS_FORMAT_JSON = "json"
+	CREDENTIALS_JSON_SUFFIX = ".json"
+)
+
+type jsonCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      string `json:",omitempty"`
+}
+
+// json credentials are written next to the ini credentials file, e.g. ~/.aws/credentials.json
+func (pw *ProfileWriter) jsonCredentialsPath() string {
+	return pw.credentialsPath + CREDENTIALS_JSON_SUFFIX
+}
+
+// profiles of the json credentials file at path, a missing file has none
+func readJsonCredentials(path string) (map[string]jsonCredentials, error) {
+	profiles := map[string]jsonCredentials{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &profiles); err != nil {
+		return nil, fmt.Errorf("Invalid json credentials file %s: %s", path, err)
+	}
+	return profiles, nil
+}
+
+// write cred as profile into the json credentials file, keeping all other profiles
+func (pw *ProfileWriter) WriteJsonProfile(cred *sts.Credentials, profileName *string) error {
+	pw.acquire_lock()
+	defer pw.release_lock()
+
+	if err := pw.checkProtected(*profileName); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(pw.awsPath, os.ModePerm); err != nil {
+		return fmt.Errorf("Error creating aws config path %s: %s", pw.awsPath, err)
+	}
+	path := pw.jsonCredentialsPath()
+	profiles, err := readJsonCredentials(path)
+	if err != nil {
+		return err
+	}
+	c := jsonCredentials{
+		AccessKeyId:     *cred.AccessKeyId,
+		SecretAccessKey: *cred.SecretAccessKey,
+		SessionToken:    *cred.SessionToken,
+	}
+	if cred.Expiration != nil {
+		c.Expiration = cred.Expiration.UTC().Format(time.RFC3339)
+	}
+	profiles[*profileName] = c
+	if err := writeFileAtomic(path, 0600, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(profiles)
+	}); err != nil {
+		return fmt.Errorf("Error writing json credentials file: %s", err)
+	}
+
+	printer.Printf("Wrote session token for profile %s to %s\n", *profileName, path)
+	printer.Printf("Token is valid until: %v\n", cred.Expiration)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsJson_WriteJsonProfile(t *testing.T) {
+	credPath := path.Join(os.TempDir(), "swamp-test-credentials")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credPath)
+	defer os.Clearenv()
+	jsonPath := credPath + CREDENTIALS_JSON_SUFFIX
+	defer os.Remove(jsonPath)
+	ioutil.WriteFile(jsonPath, []byte(`{"other-profile": {"AccessKeyId": "other-key", "SecretAccessKey": "other-secret", "SessionToken": "other-token"}}`), 0600)
+
+	pw, _ := NewProfileWriter()
+	cred := testCredentials()
+	cred.SetExpiration(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, pw.WriteJsonProfile(cred, aws.String("some-profile")))
+
+	profiles, err := readJsonCredentials(jsonPath)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonCredentials{
+		AccessKeyId:     "some-access-key",
+		SecretAccessKey: "some-secret-access-key",
+		SessionToken:    "some-session-token",
+		Expiration:      "2021-05-01T12:00:00Z",
+	}, profiles["some-profile"])
+	assert.Equal(t, "other-key", profiles["other-profile"].AccessKeyId)
+	_, err = os.Stat(credPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCredentialsJson_ReadInvalidFile(t *testing.T) {
+	jsonPath := path.Join(os.TempDir(), "swamp-test-credentials.json")
+	defer os.Remove(jsonPath)
+	ioutil.WriteFile(jsonPath, []byte("[default]\n"), 0600)
+
+	_, err := readJsonCredentials(jsonPath)
+	assert.Error(t, err)
+
+	profiles, err := readJsonCredentials(jsonPath + ".missing")
+	assert.NoError(t, err)
+	assert.Empty(t, profiles)
+}
+
+func TestCredentialsJson_Validate(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.credentialsFileFormat = CREDENTIALS_FORMAT_JSON
+
+	assert.NoError(t, c.Validate())
+
+	c.exec = "terraform plan"
+	assert.EqualError(t, c.Validate(), "Credentials file format json requires -exec-env=credentials for -exec")
+	c.execEnv = EXEC_ENV_CREDENTIALS
+	assert.NoError(t, c.Validate())
+
+	c.credentialsFileFormat = "yaml"
+	assert.EqualError(t, c.Validate(), "Invalid value for credentials-file-format: yaml")
+}
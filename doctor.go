@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	MAX_CLOCK_SKEW  = 30 * time.Second
+	CLOCK_SKEW_HINT = "Sync your clock, mfa tokens depend on it."
+)
+
+// A checkResult is the outcome of a single doctor check.
+type checkResult struct {
+	name    string
+	err     error
+	hint    string
+	skipped bool
+}
+
+func writeCheckResults(w io.Writer, results []checkResult) bool {
+	ok := true
+	for _, r := range results {
+		switch {
+		case r.skipped:
+			fmt.Fprintf(w, "[skip] %s\n", r.name)
+		case r.err == nil:
+			fmt.Fprintf(w, "[ok]   %s\n", r.name)
+		default:
+			ok = false
+			fmt.Fprintf(w, "[fail] %s: %s\n", r.name, r.err)
+			if r.hint != "" {
+				fmt.Fprintf(w, "       %s\n", r.hint)
+			}
+		}
+	}
+	return ok
+}
+
+// A clockSkewError tells the local clock is off, other errors of a skew check tell nothing about the clock.
+type clockSkewError struct {
+	skew time.Duration
+}
+
+func (e *clockSkewError) Error() string {
+	return fmt.Sprintf("Local clock is off by %v", e.skew.Truncate(time.Second))
+}
+
+// the local clock may be off from the server's date by max
+func checkClockSkew(serverDate string, now time.Time, max time.Duration) error {
+	date, err := http.ParseTime(serverDate)
+	if err != nil {
+		return fmt.Errorf("Unable to parse server date %s", serverDate)
+	}
+	skew := now.Sub(date)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > max {
+		return &clockSkewError{skew}
+	}
+	return nil
+}
+
+// fail before spending an mfa token code if the local clock is off by more than max compared to sts
+func checkSessionClockSkew(sess *session.Session, max time.Duration) error {
+	req, _ := sts.New(sess).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	if err := req.Send(); err != nil {
+		// sts rejects requests signed with a clock off by too much, its response still tells the date
+		if req.HTTPResponse != nil {
+			if skewErr, ok := checkClockSkew(req.HTTPResponse.Header.Get("Date"), clock.Now(), max).(*clockSkewError); ok {
+				return skewErr
+			}
+		}
+		return err
+	}
+	return checkClockSkew(req.HTTPResponse.Header.Get("Date"), clock.Now(), max)
+}
+
+// run read-only checks of the current setup
+func runDoctor(config *SwampConfig) []checkResult {
+	var results []checkResult
+	profile := guessCurrentProfile(config)
+
+	if config.region == "" {
+		results = append(results, checkResult{name: "Region", err: errors.New("No region set"), hint: "Set -region or AWS_REGION."})
+	} else {
+		results = append(results, checkResult{name: "Region " + config.region, err: validateRegion(config.region)})
+	}
+
+	sess, err := session.NewSessionWithOptions(getBaseSessionOptions(config))
+	if err == nil {
+		_, err = sess.Config.Credentials.Get()
+	}
+	results = append(results, checkResult{
+		name: "Base credentials of profile " + profile,
+		err:  err,
+		hint: fmt.Sprintf(`Run "aws configure --profile %s".`, profile),
+	})
+	if err != nil {
+		return results
+	}
+
+	req, output := sts.New(sess).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	err = req.Send()
+	results = append(results, checkResult{name: "Get caller identity", err: err, hint: invalidCredentialsHint(profile)})
+	if err != nil {
+		return results
+	}
+	results = append(results, checkResult{
+		name: "Clock skew",
+		err:  checkClockSkew(req.HTTPResponse.Header.Get("Date"), clock.Now(), MAX_CLOCK_SKEW),
+		hint: CLOCK_SKEW_HINT,
+	})
+
+	if config.tokenSerialNumber == "" {
+		results = append(results, checkResult{name: "Mfa device", skipped: true})
+	} else {
+		results = append(results, checkResult{
+			name: "Mfa device " + config.tokenSerialNumber,
+			err:  checkMfaDevice(sess, *output.Arn, config.tokenSerialNumber),
+			hint: "Pass the serial or arn of an mfa device assigned to your user.",
+		})
+	}
+
+	if config.targetRole == "" {
+		results = append(results, checkResult{name: "Target role", skipped: true})
+	} else {
+		roleArn := *config.GetRoleArn()
+		if account := arnAccount(roleArn); account != *output.Account {
+			results = append(results, checkResult{name: "Target role " + roleArn + " (in account " + account + ", iam:GetRole must run there)", skipped: true})
+		} else if _, err := iam.New(sess).GetRole(&iam.GetRoleInput{RoleName: aws.String(roleNameFromArn(roleArn))}); err != nil {
+			results = append(results, checkResult{name: "Target role " + roleArn + " (not readable from base account)", skipped: true})
+		} else {
+			results = append(results, checkResult{name: "Target role " + roleArn})
+		}
+	}
+	return results
+}
+
+// check that serial belongs to the caller, skipped silently without iam permissions
+func checkMfaDevice(sess *session.Session, callerArn, serial string) error {
+	output, err := iam.New(sess).ListMFADevices(&iam.ListMFADevicesInput{})
+	if err != nil {
+		return nil
+	}
+	for _, device := range output.MFADevices {
+		if *device.SerialNumber == serial {
+			return nil
+		}
+	}
+	return fmt.Errorf("Mfa device is not assigned to %s", callerArn)
+}
+
+func doctor(w io.Writer, config *SwampConfig) bool {
+	resolveRegion(config, detectEc2Region)
+	return writeCheckResults(w, runDoctor(config))
+}
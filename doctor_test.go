@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctor_WriteCheckResults(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	ok := writeCheckResults(buf, []checkResult{
+		{name: "Region eu-central-1"},
+		{name: "Target role", skipped: true},
+		{name: "Get caller identity", err: errors.New("some error"), hint: "some hint"},
+	})
+
+	assert.False(t, ok)
+	assert.Equal(t, `[ok]   Region eu-central-1
+[skip] Target role
+[fail] Get caller identity: some error
+       some hint
+`, buf.String())
+}
+
+func TestDoctor_WriteCheckResultsOk(t *testing.T) {
+	assert.True(t, writeCheckResults(new(bytes.Buffer), []checkResult{{name: "Region eu-central-1"}}))
+}
+
+func TestDoctor_CheckClockSkew(t *testing.T) {
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, checkClockSkew("Thu, 06 Jul 2017 08:00:10 GMT", now, MAX_CLOCK_SKEW))
+	assert.EqualError(t, checkClockSkew("Thu, 06 Jul 2017 08:05:00 GMT", now, MAX_CLOCK_SKEW), "Local clock is off by 5m0s")
+	assert.IsType(t, &clockSkewError{}, checkClockSkew("Thu, 06 Jul 2017 08:05:00 GMT", now, MAX_CLOCK_SKEW))
+	err := checkClockSkew("yesterday", now, MAX_CLOCK_SKEW)
+	assert.Error(t, err)
+	_, isSkew := err.(*clockSkewError)
+	assert.False(t, isSkew)
+}
+
+func TestDoctor_CheckSessionClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", "Thu, 06 Jul 2017 08:00:00 GMT")
+		fmt.Fprint(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::123456789012:user/john.doe</Arn>
+    <UserId>AIDAEXAMPLE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`)
+	}))
+	defer server.Close()
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("eu-central-1"),
+		Credentials: credentials.NewStaticCredentials("some-access-key", "some-secret-access-key", ""),
+	}))
+	clock = newFakeClock(time.Date(2017, 7, 6, 8, 0, 45, 0, time.UTC))
+	defer func() { clock = realClock{} }()
+
+	assert.NoError(t, checkSessionClockSkew(sess, time.Minute))
+	assert.EqualError(t, checkSessionClockSkew(sess, 30*time.Second), "Local clock is off by 45s")
+}
+
+func TestDoctor_CheckSessionClockSkewOfRejectedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", "Thu, 06 Jul 2017 08:00:00 GMT")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>SignatureDoesNotMatch</Code>
+    <Message>Signature expired: 20170706T082000Z is now earlier than 20170706T081500Z (20170706T082000Z - 5 min.)</Message>
+  </Error>
+</ErrorResponse>`)
+	}))
+	defer server.Close()
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("eu-central-1"),
+		Credentials: credentials.NewStaticCredentials("some-access-key", "some-secret-access-key", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+	fc := newFakeClock(time.Date(2017, 7, 6, 8, 20, 0, 0, time.UTC))
+	clock = fc
+	defer func() { clock = realClock{} }()
+
+	err := checkSessionClockSkew(sess, 30*time.Second)
+	assert.EqualError(t, err, "Local clock is off by 20m0s")
+
+	fc.now = time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	err = checkSessionClockSkew(sess, 30*time.Second)
+	assert.Error(t, err)
+	_, isSkew := err.(*clockSkewError)
+	assert.False(t, isSkew)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	SHELL_SH         = "sh"
+	SHELL_FISH       = "fish"
+	SHELL_POWERSHELL = "powershell"
+)
+
+var envPrefixPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func validateEnvExport(shell, prefix string) error {
+	switch shell {
+	case SHELL_SH, SHELL_FISH, SHELL_POWERSHELL:
+	default:
+		return fmt.Errorf("Invalid value for shell: %s", shell)
+	}
+	if prefix != "" && !envPrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("Invalid value for env-prefix, must be a valid variable name: %s", prefix)
+	}
+	return nil
+}
+
+func exportStatement(shell, name, value string) string {
+	switch shell {
+	case SHELL_FISH:
+		return fmt.Sprintf("set -gx %s %s;", name, shellQuote(value))
+	case SHELL_POWERSHELL:
+		return fmt.Sprintf("$env:%s = '%s'", name, strings.Replace(value, "'", "''", -1))
+	default:
+		return fmt.Sprintf("export %s=%s", name, shellQuote(value))
+	}
+}
+
+func unsetStatement(shell, name string) string {
+	switch shell {
+	case SHELL_FISH:
+		return fmt.Sprintf("set -e %s;", name)
+	case SHELL_POWERSHELL:
+		return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", name)
+	default:
+		return fmt.Sprintf("unset %s", name)
+	}
+}
+
+// print statements exporting cred as prefixed AWS_* variables for eval in shell,
+// without prefix a profile set in the shell is unset as well unless noUnset is given
+func writeEnvExport(w io.Writer, shell, prefix string, noUnset bool, cred *sts.Credentials, region string) error {
+	if prefix == "" && !noUnset {
+		for _, name := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+			if _, err := fmt.Fprintln(w, unsetStatement(shell, name)); err != nil {
+				return err
+			}
+		}
+	}
+	vars := [][]string{
+		{"AWS_ACCESS_KEY_ID", *cred.AccessKeyId},
+		{"AWS_SECRET_ACCESS_KEY", *cred.SecretAccessKey},
+		{"AWS_SESSION_TOKEN", *cred.SessionToken},
+	}
+	if region != "" {
+		vars = append(vars, []string{"AWS_REGION", region})
+	}
+	for _, v := range vars {
+		if _, err := fmt.Fprintln(w, exportStatement(shell, prefix+v[0], v[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvExport_WriteEnvExport(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, writeEnvExport(buf, SHELL_SH, "PROD_", false, testCredentials().SetSecretAccessKey("some-secret/access+key"), "eu-central-1"))
+	assert.Equal(t, `export PROD_AWS_ACCESS_KEY_ID=some-access-key
+export PROD_AWS_SECRET_ACCESS_KEY=some-secret/access+key
+export PROD_AWS_SESSION_TOKEN=some-session-token
+export PROD_AWS_REGION=eu-central-1
+`, buf.String())
+}
+
+func TestEnvExport_WriteEnvExportFishAndPowershell(t *testing.T) {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, writeEnvExport(buf, SHELL_FISH, "", false, testCredentials().SetSecretAccessKey("some-secret/access+key"), ""))
+	assert.Contains(t, buf.String(), "set -e AWS_PROFILE;\n")
+	assert.Contains(t, buf.String(), "set -gx AWS_SESSION_TOKEN some-session-token;\n")
+
+	buf.Reset()
+	assert.NoError(t, writeEnvExport(buf, SHELL_POWERSHELL, "DEV_", false, testCredentials().SetSecretAccessKey("some-secret/access+key"), ""))
+	assert.Contains(t, buf.String(), "$env:DEV_AWS_SESSION_TOKEN = 'some-session-token'\n")
+}
+
+func TestEnvExport_WriteEnvExportUnset(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, writeEnvExport(buf, SHELL_SH, "", false, testCredentials().SetSecretAccessKey("some-secret/access+key"), ""))
+	assert.Equal(t, `unset AWS_PROFILE
+unset AWS_DEFAULT_PROFILE
+export AWS_ACCESS_KEY_ID=some-access-key
+export AWS_SECRET_ACCESS_KEY=some-secret/access+key
+export AWS_SESSION_TOKEN=some-session-token
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeEnvExport(buf, SHELL_SH, "", true, testCredentials().SetSecretAccessKey("some-secret/access+key"), ""))
+	assert.NotContains(t, buf.String(), "unset")
+}
+
+func TestEnvExport_ValidateEnvExport(t *testing.T) {
+	assert.NoError(t, validateEnvExport(SHELL_SH, "PROD_"))
+	assert.Error(t, validateEnvExport("csh", ""))
+	assert.Error(t, validateEnvExport(SHELL_SH, "PROD-"))
+}
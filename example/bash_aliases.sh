@@ -325,7 +325,7 @@ function swamp-team3-nonlive-users-developer() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Developer' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' "${@}"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -target-duration 14400 "${@}"
 }
 
 function swamp-team3-nonlive-users-developer-bash() {
@@ -333,7 +333,7 @@ function swamp-team3-nonlive-users-developer-bash() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Developer' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -exec "bash"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -target-duration 14400 -exec "bash"
 }
 
 function swamp-team3-nonlive-users-developer-info() {
@@ -341,7 +341,7 @@ function swamp-team3-nonlive-users-developer-info() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Developer' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -exec "aws sts get-caller-identity --output json"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -target-duration 14400 -exec "aws sts get-caller-identity --output json"
 }
 
 function swamp-team3-nonlive-users-developer-tf-init() {
@@ -349,7 +349,7 @@ function swamp-team3-nonlive-users-developer-tf-init() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Developer' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -exec "cd '${1}' && terraform init"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -target-duration 14400 -exec "cd '${1}' && terraform init"
 }
 
 function swamp-team3-nonlive-users-developer-build() {
@@ -357,7 +357,7 @@ function swamp-team3-nonlive-users-developer-build() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Developer' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -exec "./ci/build.sh"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -target-duration 14400 -exec "./ci/build.sh"
 }
 
 function swamp-team3-nonlive-users-developer-deploy() {
@@ -365,7 +365,7 @@ function swamp-team3-nonlive-users-developer-deploy() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Developer' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -exec "./ci/deploy.sh \${SWAMP_ACCOUNT_NAME}"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -target-duration 14400 -exec "./ci/deploy.sh \${SWAMP_ACCOUNT_NAME}"
 }
 
 function swamp-team3-nonlive-users-developer-tf-plan() {
@@ -373,7 +373,7 @@ function swamp-team3-nonlive-users-developer-tf-plan() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Developer' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -exec "cd '${1}' && terraform workspace select \${SWAMP_ACCOUNT_NAME} && terraform plan"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Developer' -target-profile 'team3-nonlive-users-developer' -target-duration 14400 -exec "cd '${1}' && terraform workspace select \${SWAMP_ACCOUNT_NAME} && terraform plan"
 }
 
 function swamp-team3-nonlive-users-admin() {
@@ -381,7 +381,7 @@ function swamp-team3-nonlive-users-admin() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Admin' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' "${@}"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -target-duration 14400 "${@}"
 }
 
 function swamp-team3-nonlive-users-admin-bash() {
@@ -389,7 +389,7 @@ function swamp-team3-nonlive-users-admin-bash() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Admin' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -exec "bash"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -target-duration 14400 -exec "bash"
 }
 
 function swamp-team3-nonlive-users-admin-info() {
@@ -397,7 +397,7 @@ function swamp-team3-nonlive-users-admin-info() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Admin' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -exec "aws sts get-caller-identity --output json"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -target-duration 14400 -exec "aws sts get-caller-identity --output json"
 }
 
 function swamp-team3-nonlive-users-admin-tf-init() {
@@ -405,7 +405,7 @@ function swamp-team3-nonlive-users-admin-tf-init() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Admin' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -exec "cd '${1}' && terraform init"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -target-duration 14400 -exec "cd '${1}' && terraform init"
 }
 
 function swamp-team3-nonlive-users-admin-build() {
@@ -413,7 +413,7 @@ function swamp-team3-nonlive-users-admin-build() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Admin' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -exec "./ci/build.sh"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -target-duration 14400 -exec "./ci/build.sh"
 }
 
 function swamp-team3-nonlive-users-admin-deploy() {
@@ -421,7 +421,7 @@ function swamp-team3-nonlive-users-admin-deploy() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Admin' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -exec "./ci/deploy.sh \${SWAMP_ACCOUNT_NAME}"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -target-duration 14400 -exec "./ci/deploy.sh \${SWAMP_ACCOUNT_NAME}"
 }
 
 function swamp-team3-nonlive-users-admin-tf-plan() {
@@ -429,7 +429,7 @@ function swamp-team3-nonlive-users-admin-tf-plan() {
     SWAMP_ACCOUNT='XXXXXXXXXXX3' \
     SWAMP_ACCOUNT_NAME='nonlive' \
     SWAMP_TARGET_ROLE='users/Admin' \
-    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -exec "cd '${1}' && terraform workspace select \${SWAMP_ACCOUNT_NAME} && terraform plan"
+    swamp -region eu-central-1 -profile team3 -intermediate-profile team3-session -mfa-device arn:aws:iam::CCCCCCCCCC:mfa/BBBBBBBB -mfa-exec 'pass otp aws.amazon.com/team3' -account 'XXXXXXXXXXX3' -target-role 'users/Admin' -target-profile 'team3-nonlive-users-admin' -target-duration 14400 -exec "cd '${1}' && terraform workspace select \${SWAMP_ACCOUNT_NAME} && terraform plan"
 }
 
 function swamp-team3-live-users-developer() {
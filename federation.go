@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	MIN_FEDERATION_NAME_LEN = 2
+	MAX_FEDERATION_NAME_LEN = 32
+	MIN_FEDERATION_DURATION = 900
+	MAX_FEDERATION_DURATION = 129600
+)
+
+var federationNamePattern = regexp.MustCompile(`^[\w+=,.@-]+$`)
+
+func validateFederationName(name string) error {
+	if len(name) < MIN_FEDERATION_NAME_LEN || len(name) > MAX_FEDERATION_NAME_LEN {
+		return fmt.Errorf("Invalid federation name %s, must be between %d and %d characters", name, MIN_FEDERATION_NAME_LEN, MAX_FEDERATION_NAME_LEN)
+	}
+	if !federationNamePattern.MatchString(name) {
+		return fmt.Errorf("Invalid federation name %s, only alphanumeric characters and +=,.@-_ are allowed", name)
+	}
+	return nil
+}
+
+// federated users get the permissions of the user intersected with the session policies, no policy grants nothing
+func (config *SwampConfig) validateFederation() error {
+	if err := validateFederationName(config.federationName); err != nil {
+		return err
+	}
+	if err := checkStringFlagNotEmpty("target-profile", config.targetProfile); err != nil {
+		return err
+	}
+	if config.targetRole != "" || config.targetAccount != "" || config.roleChainFile != "" {
+		return errors.New("Federation name is mutual exclusive with target role, account and assume role chain file")
+	}
+	if config.tokenSerialNumber != "" {
+		return errors.New("Federation name and mfa device are mutual exclusive")
+	}
+	if config.policy == "" && len(config.policyArns) == 0 {
+		return errors.New("Federation name requires -policy or -policy-arn")
+	}
+	if config.targetDurationMax || config.targetDuration < MIN_FEDERATION_DURATION || config.targetDuration > MAX_FEDERATION_DURATION {
+		return fmt.Errorf("Invalid target duration for federation: must be between %d and %d seconds", MIN_FEDERATION_DURATION, MAX_FEDERATION_DURATION)
+	}
+	return nil
+}
+
+// get-federation-token with the long-term credentials of baseProfile, scoped down by the session policies
+func getFederationToken(config *SwampConfig, sess *session.Session, baseProfile string) *sts.Credentials {
+	svc := sts.New(sess)
+	input := &sts.GetFederationTokenInput{
+		Name:            &config.federationName,
+		DurationSeconds: &config.targetDuration,
+	}
+	if len(config.policyArns) > 0 {
+		input.PolicyArns = toStsPolicyArns(config.policyArns)
+	}
+	if config.policy != "" {
+		input.Policy = &config.policy
+	}
+	if tags, err := config.GetSessionTags(); err != nil {
+		die("Error reading session tags", err)
+	} else if len(tags) > 0 {
+		input.Tags = toStsTags(tags)
+	}
+	output, err := svc.GetFederationToken(input)
+	if err != nil {
+		if isPackedPolicyTooLarge(err) {
+			dieSlow("Error getting federation token", "The session policies exceed the size limit of sts, use fewer or smaller policies.", err)
+		}
+		dieSlow("Error getting federation token", fmt.Sprintf(`Get-federation-token requires long-term credentials of an iam user, make sure profile %s has them and allows running "aws sts get-federation-token".`, baseProfile), err)
+	}
+	warnOnPackedPolicySize(output.PackedPolicySize)
+	return output.Credentials
+}
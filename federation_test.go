@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFederationConfig() *SwampConfig {
+	c := NewSwampConfig()
+	c.federationName = "ci-runner"
+	c.policy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`
+	return c
+}
+
+func TestFederation_ValidateName(t *testing.T) {
+	assert.NoError(t, validateFederationName("ci-runner@build+1"))
+	assert.Error(t, validateFederationName("x"))
+	assert.Error(t, validateFederationName(strings.Repeat("x", 33)))
+	assert.Error(t, validateFederationName("ci runner"))
+}
+
+func TestFederation_Validate(t *testing.T) {
+	c := testFederationConfig()
+	assert.NoError(t, c.Validate())
+
+	c.printEnvExport = true
+	assert.NoError(t, c.Validate())
+}
+
+func TestFederation_ValidateRequiresPolicy(t *testing.T) {
+	c := testFederationConfig()
+	c.policy = ""
+	assert.EqualError(t, c.Validate(), "Federation name requires -policy or -policy-arn")
+
+	c.policyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+	assert.NoError(t, c.Validate())
+
+	c.policy = strings.Repeat("x", MAX_POLICY_SIZE+1)
+	assert.EqualError(t, c.Validate(), "Session policies have 2087 characters, sts allows at most 2048 for the inline policy and policy arns together")
+}
+
+func TestFederation_ValidateConflicts(t *testing.T) {
+	c := testFederationConfig()
+	c.targetRole = "some-role"
+	assert.Error(t, c.Validate())
+
+	c = testFederationConfig()
+	c.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/some-user"
+	assert.EqualError(t, c.Validate(), "Federation name and mfa device are mutual exclusive")
+
+	c = testFederationConfig()
+	c.targetDuration = 600
+	assert.Error(t, c.Validate())
+	c.targetDuration = MAX_FEDERATION_DURATION
+	assert.NoError(t, c.Validate())
+}
+
+func TestFederation_GetFederationToken(t *testing.T) {
+	var form string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.Form.Encode()
+		fmt.Fprint(w, `<GetFederationTokenResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetFederationTokenResult>
+    <Credentials>
+      <AccessKeyId>some-access-key</AccessKeyId>
+      <SecretAccessKey>some-secret-access-key</SecretAccessKey>
+      <SessionToken>some-session-token</SessionToken>
+      <Expiration>2021-05-01T12:00:00Z</Expiration>
+    </Credentials>
+    <FederatedUser>
+      <Arn>arn:aws:sts::123456789012:federated-user/ci-runner</Arn>
+      <FederatedUserId>123456789012:ci-runner</FederatedUserId>
+    </FederatedUser>
+  </GetFederationTokenResult>
+</GetFederationTokenResponse>`)
+	}))
+	defer server.Close()
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("eu-central-1"),
+		Credentials: credentials.NewStaticCredentials("some-user-key", "some-user-secret", ""),
+	}))
+
+	cred := getFederationToken(testFederationConfig(), sess, "default")
+
+	assert.Equal(t, "some-session-token", *cred.SessionToken)
+	assert.Contains(t, form, "Action=GetFederationToken")
+	assert.Contains(t, form, "Name=ci-runner")
+	assert.Contains(t, form, "Policy=")
+}
@@ -3,7 +3,7 @@ module github.com/felixb/swamp
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.35.2
+	github.com/aws/aws-sdk-go v1.38.30
 	github.com/go-ini/ini v1.61.0
 	github.com/golang-interfaces/ios v0.0.0-20170803194714-da59acb78efc // indirect
 	github.com/golang-utils/lockfile v0.0.0-20170803195317-342df9650a96
@@ -12,7 +12,6 @@ require (
 	github.com/onsi/gomega v1.10.2 // indirect
 	github.com/smartystreets/goconvey v1.6.4 // indirect
 	github.com/stretchr/testify v1.4.0
-	golang.org/x/net v0.0.0-20201002202402-0a1ea396d57c // indirect
 	gopkg.in/ini.v1 v1.61.0 // indirect
 	gopkg.in/yaml.v2 v2.3.0
 )
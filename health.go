@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A healthState tracks the expiration of the latest written credentials
+// for liveness and readiness probes in renew mode.
+type healthState struct {
+	mu         sync.Mutex
+	expiration *time.Time
+}
+
+// Default health state.
+var health = &healthState{}
+
+func (h *healthState) SetExpiration(expiration *time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expiration = expiration
+}
+
+func (h *healthState) Expiration() *time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.expiration
+}
+
+// ready reports whether credentials have been written and are not expired yet
+func (h *healthState) ready(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.expiration != nil && now.Before(*h.expiration)
+}
+
+func (h *healthState) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !h.ready(clock.Now()) {
+			http.Error(w, "credentials expired", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+func serveHealth(addr string, h *healthState) {
+	go func() {
+		if err := http.ListenAndServe(addr, h.Handler()); err != nil {
+			die("Error serving health endpoint", err)
+		}
+	}()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth_Healthz(t *testing.T) {
+	h := &healthState{}
+	rec := httptest.NewRecorder()
+
+	h.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHealth_ReadyzWithoutCredentials(t *testing.T) {
+	h := &healthState{}
+	rec := httptest.NewRecorder()
+
+	h.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestHealth_ReadyzWithValidCredentials(t *testing.T) {
+	h := &healthState{}
+	expiration := time.Now().Add(time.Hour)
+	h.SetExpiration(&expiration)
+	rec := httptest.NewRecorder()
+
+	h.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHealth_ReadyzWithExpiredCredentials(t *testing.T) {
+	h := &healthState{}
+	expiration := time.Now().Add(-time.Minute)
+	h.SetExpiration(&expiration)
+	rec := httptest.NewRecorder()
+
+	h.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
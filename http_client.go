@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// http client used for all aws sessions, nil for the sdk default
+var sessionHTTPClient *http.Client
+
+// print timings of aws requests, set with -verbose
+var traceRequests bool
+
+func setupHTTPClient(config *SwampConfig) error {
+	var transport http.RoundTripper = http.DefaultTransport
+	if config.caBundle != "" {
+		t, err := caBundleTransport(config.caBundle)
+		if err != nil {
+			return err
+		}
+		transport = t
+	}
+	if config.noVerifySsl {
+		fmt.Fprintln(os.Stderr, "WARNING: tls certificates of aws endpoints are not verified, never use -no-verify-ssl outside of tests!")
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		transport = t
+	}
+	traceRequests = config.verbose
+	if transport != http.DefaultTransport {
+		sessionHTTPClient = &http.Client{Transport: transport}
+	}
+	return nil
+}
+
+// default transport trusting only the certificates in the pem file at path
+func caBundleTransport(path string) (*http.Transport, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading ca bundle: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("Error reading ca bundle: no certificates found in %s", path)
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return t, nil
+}
+
+type requestTraceKey struct{}
+
+// phase timings of one attempt of an aws request
+type requestTrace struct {
+	start, dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone, firstByte time.Time
+}
+
+// sdk default handlers, with -verbose extended by printing timings of all phases of each request;
+// tracing hooks into the handlers instead of wrapping the transport as the sdk expects an *http.Transport
+// to load AWS_CA_BUNDLE into
+func sessionHandlers() request.Handlers {
+	handlers := defaults.Handlers()
+	if traceRequests {
+		handlers.Send.PushFrontNamed(request.NamedHandler{Name: "swamp.StartTrace", Fn: startTrace})
+		handlers.Send.PushBackNamed(request.NamedHandler{Name: "swamp.PrintTrace", Fn: printTrace})
+	}
+	return handlers
+}
+
+func startTrace(r *request.Request) {
+	t := &requestTrace{}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+	ctx := context.WithValue(httptrace.WithClientTrace(r.HTTPRequest.Context(), trace), requestTraceKey{}, t)
+	r.HTTPRequest = r.HTTPRequest.WithContext(ctx)
+	t.start = time.Now()
+}
+
+func printTrace(r *request.Request) {
+	t, ok := r.HTTPRequest.Context().Value(requestTraceKey{}).(*requestTrace)
+	if !ok {
+		return
+	}
+	printer.Printf("%s %s: dns %v, connect %v, tls %v, first byte %v, total %v\n", r.Operation.Name, r.HTTPRequest.URL.Host,
+		phase(t.dnsStart, t.dnsDone), phase(t.connectStart, t.connectDone), phase(t.tlsStart, t.tlsDone), phase(t.start, t.firstByte), time.Since(t.start))
+}
+
+// duration of a phase, 0 if the phase was skipped e.g. on reused connections
+func phase(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+const callerIdentityResponse = `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::123456789012:user/john.doe</Arn>
+    <UserId>AIDAEXAMPLE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`
+
+// get caller identity from server with a session set up like all aws sessions of swamp
+func getTestCallerIdentity(t *testing.T, server *httptest.Server) error {
+	profile, region := "default", "eu-central-1"
+	options := newSessionOptions(&profile, &region)
+	options.Config.Endpoint = aws.String(server.URL)
+	options.Config.Credentials = credentials.NewStaticCredentials("some-access-key", "some-secret-key", "")
+	sess, err := session.NewSessionWithOptions(options)
+	if !assert.NoError(t, err) {
+		return err
+	}
+	_, err = sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	return err
+}
+
+func TestHttpClient_TraceRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, callerIdentityResponse)
+	}))
+	defer server.Close()
+	buf := new(bytes.Buffer)
+	printer.SetOutput(buf)
+	defer printer.SetOutput(os.Stdout)
+	defer func() { traceRequests = false }()
+
+	config := NewSwampConfig()
+	config.caBundle = ""
+	config.verbose = true
+	assert.NoError(t, setupHTTPClient(config))
+
+	assert.NoError(t, getTestCallerIdentity(t, server))
+	assert.Regexp(t, `^GetCallerIdentity 127\.0\.0\.1:\d+: dns .*, total .*\n$`, buf.String())
+}
+
+func TestHttpClient_Phase(t *testing.T) {
+	start := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Second, phase(start, start.Add(time.Second)))
+	assert.Equal(t, time.Duration(0), phase(time.Time{}, start))
+}
+
+func TestHttpClient_SetupHTTPClientWithCaBundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caBundle := path.Join(os.TempDir(), "swamp-ca-bundle-test.pem")
+	defer os.Remove(caBundle)
+	ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	defer func() { sessionHTTPClient = nil }()
+
+	config := NewSwampConfig()
+	config.caBundle = caBundle
+	assert.NoError(t, setupHTTPClient(config))
+
+	_, err := sessionHTTPClient.Get(server.URL)
+	assert.NoError(t, err)
+
+	config.caBundle = "example/swamp.yaml"
+	assert.Error(t, setupHTTPClient(config))
+}
+
+func TestHttpClient_SetupHTTPClientWithNoVerifySsl(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	defer func() { sessionHTTPClient = nil }()
+
+	config := NewSwampConfig()
+	config.caBundle = ""
+	config.noVerifySsl = true
+	assert.NoError(t, setupHTTPClient(config))
+
+	_, err := sessionHTTPClient.Get(server.URL)
+	assert.NoError(t, err)
+
+	config.caBundle = "some-ca-bundle.pem"
+	assert.Error(t, setupHTTPClient(config))
+}
+
+func TestHttpClient_SetupHTTPClientWithCaBundleFromEnvAndVerbose(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, callerIdentityResponse)
+	}))
+	defer server.Close()
+	dir, _ := ioutil.TempDir("", "swamp-ca-bundle-test")
+	defer os.RemoveAll(dir)
+	caBundle := path.Join(dir, "ca-bundle.pem")
+	ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	os.Setenv("AWS_CA_BUNDLE", caBundle)
+	defer os.Unsetenv("AWS_CA_BUNDLE")
+	buf := new(bytes.Buffer)
+	printer.SetOutput(buf)
+	defer printer.SetOutput(os.Stdout)
+	defer func() { sessionHTTPClient, traceRequests = nil, false }()
+
+	config := NewSwampConfig()
+	config.verbose = true
+	assert.Equal(t, caBundle, config.caBundle)
+	assert.NoError(t, setupHTTPClient(config))
+
+	assert.NoError(t, getTestCallerIdentity(t, server))
+	assert.Contains(t, buf.String(), "GetCallerIdentity 127.0.0.1:")
+}
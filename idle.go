@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	IDLE_POLL_INTERVAL = time.Minute
+)
+
+// check whether the credentials file was read since it was written last
+func credentialsFileUsed(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return true
+	}
+	return fileAtime(fi).After(fi.ModTime())
+}
+
+// probe whether reading a file in dir advances its access time, it doesn't on noatime mounts
+func atimeUpdates(dir string) bool {
+	f, err := ioutil.TempFile(dir, ".swamp-atime-")
+	if err != nil {
+		return true
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("swamp")
+	f.Close()
+	past := time.Now().Add(-time.Hour)
+	if err != nil || os.Chtimes(f.Name(), past, past) != nil {
+		return true
+	}
+	if _, err := ioutil.ReadFile(f.Name()); err != nil {
+		return true
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		return true
+	}
+	return fileAtime(fi).After(past)
+}
+
+var (
+	atimeProbe     = atimeUpdates
+	atimeProbeOnce sync.Once
+	atimeUsable    bool
+)
+
+// block until someone consumes the credentials written last time to credentialsPath or served by cs
+func waitForConsumer(credentialsPath string, cs *credentialServer) {
+	used := func() bool {
+		if cs != nil {
+			return cs.Used()
+		}
+		return credentialsFileUsed(credentialsPath)
+	}
+	if cs == nil {
+		atimeProbeOnce.Do(func() {
+			if atimeUsable = atimeProbe(filepath.Dir(credentialsPath)); !atimeUsable {
+				printer.Printf("Access time of %s is not updated on read, e.g. mounted with noatime, renewing regardless of use\n", credentialsPath)
+			}
+		})
+		if !atimeUsable {
+			return
+		}
+	}
+	if used() {
+		return
+	}
+	printer.Printf("Credentials were not used since last refresh, pausing refresh\n")
+	for !used() {
+		clock.Sleep(IDLE_POLL_INTERVAL)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdle_CredentialsFileUsed(t *testing.T) {
+	credPath := path.Join(os.TempDir(), "swamp-idle-test.ini")
+	defer os.Remove(credPath)
+	ioutil.WriteFile(credPath, []byte("[swamp]\n"), 0600)
+
+	written := time.Now().Add(-time.Hour)
+	os.Chtimes(credPath, written, written)
+	assert.False(t, credentialsFileUsed(credPath))
+
+	os.Chtimes(credPath, written.Add(time.Minute), written)
+	assert.True(t, credentialsFileUsed(credPath))
+}
+
+func TestIdle_CredentialServerUsed(t *testing.T) {
+	cs, _ := newCredentialServer()
+	assert.False(t, cs.Used())
+
+	cs.SetCredentials(nil)
+	cs.markServed()
+	assert.True(t, cs.Used())
+
+	cs.SetCredentials(nil)
+	assert.False(t, cs.Used())
+}
+
+func TestIdle_AtimeUpdates(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "swamp-idle-test")
+	defer os.RemoveAll(dir)
+
+	atimeUpdates(dir)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestIdle_WaitForConsumerWithoutAtime(t *testing.T) {
+	credPath := path.Join(os.TempDir(), "swamp-idle-test.ini")
+	defer os.Remove(credPath)
+	ioutil.WriteFile(credPath, []byte("[swamp]\n"), 0600)
+	written := time.Now().Add(-time.Hour)
+	os.Chtimes(credPath, written, written)
+
+	atimeProbe = func(string) bool { return false }
+	defer func() {
+		atimeProbe = atimeUpdates
+		atimeProbeOnce = sync.Once{}
+	}()
+
+	// returns right away instead of waiting for a read that never shows
+	waitForConsumer(credPath, nil)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// A jsonResult describes the credentials of one run without their secrets.
+type jsonResult struct {
+	Profile     string     `json:"profile"`
+	RoleArn     string     `json:"roleArn"`
+	Region      string     `json:"region,omitempty"`
+	AccessKeyId string     `json:"accessKeyId"`
+	Expiration  *time.Time `json:"expiration"`
+}
+
+func writeJsonResult(w io.Writer, config *SwampConfig, cred *sts.Credentials) error {
+	return newJsonEncoder(w, config.jsonPretty.Or(false)).Encode(jsonResult{
+		Profile:     config.targetProfile,
+		RoleArn:     *config.GetRoleArn(),
+		Region:      config.region,
+		AccessKeyId: *cred.AccessKeyId,
+		Expiration:  cred.Expiration,
+	})
+}
+
+var (
+	jsonFdOnce sync.Once
+	jsonFdFile *os.File
+)
+
+// file of the inherited descriptor fd, opened once as closing it on garbage collection would close fd
+func jsonFdWriter(fd int) io.Writer {
+	jsonFdOnce.Do(func() {
+		jsonFdFile = os.NewFile(uintptr(fd), "json-fd")
+	})
+	return jsonFdFile
+}
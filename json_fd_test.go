@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonFd_WriteResult(t *testing.T) {
+	config := NewSwampConfig()
+	config.targetProfile = "target"
+	config.targetRole = "arn:aws:iam::123456789012:role/admin"
+	config.region = "eu-central-1"
+	cred := testCredentials()
+	cred.SetExpiration(time.Date(2017, 7, 6, 9, 0, 0, 0, time.UTC))
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, writeJsonResult(buf, config, cred))
+	assert.Equal(t, `{"profile":"target","roleArn":"arn:aws:iam::123456789012:role/admin","region":"eu-central-1","accessKeyId":"some-access-key","expiration":"2017-07-06T09:00:00Z"}`+"\n", buf.String())
+	assert.NotContains(t, buf.String(), "some-secret-access-key")
+}
+
+func TestJsonFd_WriteResultPretty(t *testing.T) {
+	config := NewSwampConfig()
+	config.targetProfile = "target"
+	config.targetRole = "arn:aws:iam::123456789012:role/admin"
+	config.jsonPretty.Set("true")
+	cred := testCredentials()
+	cred.SetExpiration(time.Date(2017, 7, 6, 9, 0, 0, 0, time.UTC))
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, writeJsonResult(buf, config, cred))
+	assert.Contains(t, buf.String(), "{\n  \"profile\": \"target\",\n")
+}
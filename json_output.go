@@ -0,0 +1,15 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// encoder writing single line json, or indented json for reading if pretty
+func newJsonEncoder(w io.Writer, pretty bool) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+	"gopkg.in/yaml.v2"
+)
+
+type k8sMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type k8sSecret struct {
+	ApiVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+// names of the secret keys for access key id, secret access key and session token
+func parseK8sSecretKeys(s string) ([]string, error) {
+	keys := strings.Split(s, ",")
+	if len(keys) != 3 {
+		return nil, fmt.Errorf("Invalid value for k8s-secret-keys, expected three comma separated keys: %s", s)
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimSpace(key)
+		if keys[i] == "" {
+			return nil, fmt.Errorf("Invalid value for k8s-secret-keys, keys must not be empty: %s", s)
+		}
+	}
+	return keys, nil
+}
+
+func renderK8sSecret(name, namespace string, keys []string, cred *sts.Credentials) ([]byte, error) {
+	encode := func(s *string) string {
+		return base64.StdEncoding.EncodeToString([]byte(*s))
+	}
+	return yaml.Marshal(k8sSecret{
+		ApiVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sMetadata{Name: name, Namespace: namespace},
+		Type:       "Opaque",
+		Data: map[string]string{
+			keys[0]: encode(cred.AccessKeyId),
+			keys[1]: encode(cred.SecretAccessKey),
+			keys[2]: encode(cred.SessionToken),
+		},
+	})
+}
+
+func writeK8sSecret(outPath, name, namespace, keys string, cred *sts.Credentials) error {
+	k, err := parseK8sSecretKeys(keys)
+	if err != nil {
+		return err
+	}
+	b, err := renderK8sSecret(name, namespace, k, cred)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(outPath, 0600, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	}); err != nil {
+		return err
+	}
+	printer.Printf("Wrote kubernetes secret %s/%s to %s\n", namespace, name, outPath)
+	return nil
+}
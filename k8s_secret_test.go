@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestK8sSecret_WriteK8sSecret(t *testing.T) {
+	outPath := path.Join(os.TempDir(), "swamp-test-secret.yaml")
+	defer os.Remove(outPath)
+
+	creds := testCredentials()
+
+	err := writeK8sSecret(outPath, "aws-creds", "default", "AWS_ACCESS_KEY_ID,AWS_SECRET_ACCESS_KEY,AWS_SESSION_TOKEN", creds)
+	assert.NoError(t, err)
+
+	b, _ := ioutil.ReadFile(outPath)
+	assert.Equal(t, `apiVersion: v1
+kind: Secret
+metadata:
+  name: aws-creds
+  namespace: default
+type: Opaque
+data:
+  AWS_ACCESS_KEY_ID: c29tZS1hY2Nlc3Mta2V5
+  AWS_SECRET_ACCESS_KEY: c29tZS1zZWNyZXQtYWNjZXNzLWtleQ==
+  AWS_SESSION_TOKEN: c29tZS1zZXNzaW9uLXRva2Vu
+`, string(b))
+}
+
+func TestK8sSecret_ParseK8sSecretKeys(t *testing.T) {
+	keys, err := parseK8sSecretKeys("access, secret, token")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"access", "secret", "token"}, keys)
+
+	_, err = parseK8sSecretKeys("access,secret")
+	assert.Error(t, err)
+
+	_, err = parseK8sSecretKeys("access,,token")
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	SECURITY_COMMAND = "/usr/bin/security"
+)
+
+// quote an argument for the command parser of security -i
+func keychainQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// security add-generic-password command read from stdin by security -i, -U overwrites an existing item of service and profile,
+// the password goes hex encoded with -X and never shows in process listings
+func keychainCommand(service, profile string, password []byte) string {
+	return strings.Join([]string{
+		"add-generic-password", "-U",
+		"-s", keychainQuote(service),
+		"-a", keychainQuote(profile),
+		"-l", keychainQuote(service + " " + profile),
+		"-X", hex.EncodeToString(password),
+	}, " ") + "\n"
+}
+
+// store credentials in the format of a credential_process as generic password in the macOS keychain
+func writeKeychain(service, profile string, cred *sts.Credentials) error {
+	b, err := json.Marshal(newProcessCredentials(cred, 0))
+	if err != nil {
+		return err
+	}
+	c := exec.Command(SECURITY_COMMAND, "-i")
+	c.Stdin = strings.NewReader(keychainCommand(service, profile, b))
+	output, err := c.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error writing keychain item %s %s: %s %s", service, profile, err, strings.TrimSpace(string(output)))
+	}
+	// security -i keeps a zero exit code on failed commands, but reports them
+	if strings.Contains(string(output), "security: ") {
+		return fmt.Errorf("Error writing keychain item %s %s: %s", service, profile, strings.TrimSpace(string(output)))
+	}
+	printer.Printf("Wrote credentials to keychain item %s %s\n", service, profile)
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeychain_Command(t *testing.T) {
+	assert.Equal(t, `add-generic-password -U -s "swamp" -a "some-profile" -l "swamp some-profile" -X 7b2256657273696f6e223a317d`+"\n",
+		keychainCommand("swamp", "some-profile", []byte(`{"Version":1}`)))
+}
+
+func TestKeychain_Quote(t *testing.T) {
+	assert.Equal(t, `"some \"quoted\" \\ profile"`, keychainQuote(`some "quoted" \ profile`))
+}
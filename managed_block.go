@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+const (
+	MANAGED_BLOCK_BEGIN = "# BEGIN swamp-managed"
+	MANAGED_BLOCK_END   = "# END swamp-managed"
+)
+
+// A managedFile is a credentials file split around the block of profiles written by swamp.
+// Content before and after the block is kept byte by byte.
+type managedFile struct {
+	before string
+	block  string
+	after  string
+}
+
+// split content at the marker lines, content without markers gets an empty block appended
+func splitManagedBlock(content string) (*managedFile, error) {
+	lines := strings.SplitAfter(content, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimRight(line, "\r\n") {
+		case MANAGED_BLOCK_BEGIN:
+			if begin >= 0 {
+				return nil, fmt.Errorf("Found more than one %s", MANAGED_BLOCK_BEGIN)
+			}
+			begin = i
+		case MANAGED_BLOCK_END:
+			if begin < 0 || end >= 0 {
+				return nil, fmt.Errorf("Found %s without %s", MANAGED_BLOCK_END, MANAGED_BLOCK_BEGIN)
+			}
+			end = i
+		}
+	}
+	if begin < 0 {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return &managedFile{before: content}, nil
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("Missing %s after %s", MANAGED_BLOCK_END, MANAGED_BLOCK_BEGIN)
+	}
+	return &managedFile{
+		before: strings.Join(lines[:begin], ""),
+		block:  strings.Join(lines[begin+1:end], ""),
+		after:  strings.Join(lines[end+1:], ""),
+	}, nil
+}
+
+func (m *managedFile) String() string {
+	block := m.block
+	if block != "" && !strings.HasSuffix(block, "\n") {
+		block += "\n"
+	}
+	return m.before + MANAGED_BLOCK_BEGIN + "\n" + block + MANAGED_BLOCK_END + "\n" + m.after
+}
+
+// profiles maintained outside the block must not be written into it, aws would find them twice
+func checkOutsideManagedBlock(m *managedFile, sectionNames ...string) error {
+	outside, err := ini.Load([]byte(m.before + m.after))
+	if err != nil {
+		return err
+	}
+	for _, name := range sectionNames {
+		if _, err := outside.GetSection(name); err == nil {
+			return fmt.Errorf("Profile %s is maintained outside the swamp managed block", name)
+		}
+	}
+	return nil
+}
+
+// like updateSection, but only the profiles between the markers are parsed and written
+func (pw *ProfileWriter) updateManagedSection(profileName *string, write func(*ini.Section) error) error {
+	if err := pw.ensureAwsPath(); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(pw.credentialsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Error reading credentials file: %s", err)
+	}
+	m, err := splitManagedBlock(string(content))
+	if err != nil {
+		return fmt.Errorf("Error reading credentials file %s: %s", pw.credentialsPath, err)
+	}
+	sectionNames := []string{*profileName}
+	if pw.combined {
+		sectionNames = append(sectionNames, configSectionName(*profileName))
+	}
+	if err := checkOutsideManagedBlock(m, sectionNames...); err != nil {
+		return err
+	}
+	cfg, err := ini.Load([]byte(m.block))
+	if err != nil {
+		return fmt.Errorf("Error reading swamp managed block: %s", err)
+	}
+	if cfg, err = pw.applyWrite(cfg, profileName, write); err != nil {
+		return err
+	}
+	block := new(strings.Builder)
+	if _, err := cfg.WriteTo(block); err != nil {
+		return err
+	}
+	m.block = block.String()
+	return pw.saveCredentialsFile(func(w io.Writer) error {
+		_, err := io.WriteString(w, m.String())
+		return err
+	})
+}
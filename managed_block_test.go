@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManualCredentials = `# maintained by hand
+[default]
+aws_access_key_id     = AKIAHANDWRITTEN
+aws_secret_access_key = hand-written-secret ; keep alignment
+`
+
+func testManagedBlockWriter(t *testing.T, content string) (*ProfileWriter, string) {
+	credPath := path.Join(os.TempDir(), "swamp-test-managed.ini")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credPath)
+	if content == "" {
+		os.Remove(credPath)
+	} else {
+		assert.NoError(t, ioutil.WriteFile(credPath, []byte(content), 0600))
+	}
+	pw, err := NewProfileWriter()
+	assert.NoError(t, err)
+	pw.managedBlock = true
+	return pw, credPath
+}
+
+func TestManagedBlock_Split(t *testing.T) {
+	m, err := splitManagedBlock("a\n" + MANAGED_BLOCK_BEGIN + "\n[x]\nk = v\n" + MANAGED_BLOCK_END + "\r\nb")
+	assert.NoError(t, err)
+	assert.Equal(t, &managedFile{before: "a\n", block: "[x]\nk = v\n", after: "b"}, m)
+
+	m, err = splitManagedBlock("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n"+MANAGED_BLOCK_BEGIN+"\n"+MANAGED_BLOCK_END+"\n", m.String())
+}
+
+func TestManagedBlock_SplitUnbalanced(t *testing.T) {
+	for _, content := range []string{
+		MANAGED_BLOCK_BEGIN + "\n",
+		MANAGED_BLOCK_END + "\n",
+		MANAGED_BLOCK_BEGIN + "\n" + MANAGED_BLOCK_BEGIN + "\n" + MANAGED_BLOCK_END + "\n",
+		MANAGED_BLOCK_BEGIN + "\n" + MANAGED_BLOCK_END + "\n" + MANAGED_BLOCK_END + "\n",
+	} {
+		_, err := splitManagedBlock(content)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestManagedBlock_RoundTripKeepsOutsideContent(t *testing.T) {
+	trailer := "\n# more hand written notes\n[other]\nregion=eu-west-1\n"
+	pw, credPath := testManagedBlockWriter(t, testManualCredentials)
+	defer os.Clearenv()
+	defer os.Remove(credPath)
+
+	assert.NoError(t, pw.WriteProfile(testCredentials().SetSessionToken("first-token"), aws.String("target"), aws.String("eu-central-1")))
+	b, _ := ioutil.ReadFile(credPath)
+	assert.True(t, strings.HasPrefix(string(b), testManualCredentials+MANAGED_BLOCK_BEGIN+"\n"))
+	assert.True(t, strings.HasSuffix(string(b), MANAGED_BLOCK_END+"\n"))
+
+	// content added after the block by hand survives the next write
+	assert.NoError(t, ioutil.WriteFile(credPath, append(b, trailer...), 0600))
+	assert.NoError(t, pw.WriteProfile(testCredentials().SetSessionToken("second-token"), aws.String("target"), aws.String("eu-central-1")))
+	assert.NoError(t, pw.WriteProfile(testCredentials().SetSessionToken("other-token"), aws.String("target-eu-west-1"), aws.String("eu-west-1")))
+
+	b, _ = ioutil.ReadFile(credPath)
+	m, err := splitManagedBlock(string(b))
+	assert.NoError(t, err)
+	assert.Equal(t, testManualCredentials, m.before)
+	assert.Equal(t, trailer, m.after)
+	assert.Contains(t, m.block, "aws_session_token     = second-token")
+	assert.Contains(t, m.block, "[target-eu-west-1]")
+	assert.NotContains(t, m.block, "first-token")
+}
+
+func TestManagedBlock_RefusesProfileOutsideBlock(t *testing.T) {
+	pw, credPath := testManagedBlockWriter(t, testManualCredentials)
+	defer os.Clearenv()
+	defer os.Remove(credPath)
+
+	err := pw.WriteProfile(testCredentials().SetSessionToken("some-token"), aws.String("default"), nil)
+	assert.EqualError(t, err, "Profile default is maintained outside the swamp managed block")
+	b, _ := ioutil.ReadFile(credPath)
+	assert.Equal(t, testManualCredentials, string(b))
+}
+
+func TestManagedBlock_NewFile(t *testing.T) {
+	pw, credPath := testManagedBlockWriter(t, "")
+	defer os.Clearenv()
+	defer os.Remove(credPath)
+
+	assert.NoError(t, pw.WriteProfile(testCredentials().SetSessionToken("some-token"), aws.String("target"), nil))
+	b, _ := ioutil.ReadFile(credPath)
+	assert.True(t, strings.HasPrefix(string(b), MANAGED_BLOCK_BEGIN+"\n[target]\n"))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+func writeMfaDevices(w io.Writer, devices []*iam.MFADevice) {
+	for _, device := range devices {
+		fmt.Fprintln(w, *device.SerialNumber)
+	}
+}
+
+// print serial numbers of the mfa devices of the base profile's user
+func listMfaDevices(w io.Writer, config *SwampConfig) error {
+	sess, err := session.NewSessionWithOptions(getBaseSessionOptions(config))
+	if err != nil {
+		return err
+	}
+	output, err := iam.New(sess).ListMFADevices(&iam.ListMFADevicesInput{})
+	if err != nil {
+		return err
+	}
+	writeMfaDevices(w, output.MFADevices)
+	return nil
+}
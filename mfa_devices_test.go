@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMfaDevices_WriteMfaDevices(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	writeMfaDevices(buf, []*iam.MFADevice{
+		{SerialNumber: aws.String("arn:aws:iam::1234567890:mfa/some-user")},
+		{SerialNumber: aws.String("GAHT12345678")},
+	})
+
+	assert.Equal(t, "arn:aws:iam::1234567890:mfa/some-user\nGAHT12345678\n", buf.String())
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// one timestamp file per target role arn, base profile and target profile
+func getMinIntervalPath(config *SwampConfig) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	key := strings.Join([]string{*config.GetRoleArn(), config.profile, config.targetProfile}, "|")
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(dir, "swamp", "last-run", hex.EncodeToString(sum[:])), nil
+}
+
+// whether the timestamp file at path was touched less than interval ago
+func ranWithin(path string, interval time.Duration, now time.Time) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return now.Sub(fi.ModTime()) < interval
+}
+
+func touchLastRun(path string, now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, []byte(now.UTC().Format(time.RFC3339)+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Chtimes(path, now, now)
+}
+
+// returns false if swamp ran less than -min-interval ago and should exit right away,
+// the returned path is touched by markMinInterval after a successful run
+func checkMinInterval(config *SwampConfig) (string, bool) {
+	if config.minInterval <= 0 {
+		return "", true
+	}
+	path, err := getMinIntervalPath(config)
+	if err != nil {
+		die("Error checking min interval", err)
+	}
+	if ranWithin(path, config.minInterval, clock.Now()) {
+		printer.Printf("Swamp ran less than %v ago, keeping profile %s\n", config.minInterval, config.targetProfile)
+		return path, false
+	}
+	return path, true
+}
+
+func markMinInterval(path string) {
+	if path == "" {
+		return
+	}
+	if err := touchLastRun(path, clock.Now()); err != nil {
+		die("Error recording last run for min interval", err)
+	}
+}
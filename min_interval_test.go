@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinInterval_CheckMinInterval(t *testing.T) {
+	os.Setenv("XDG_CACHE_HOME", os.TempDir())
+	defer os.Clearenv()
+	start := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	c := newFakeClock(start)
+	clock = c
+	defer func() { clock = realClock{} }()
+
+	config := NewSwampConfig()
+	config.targetRole = "some-min-interval-role"
+	path, err := getMinIntervalPath(config)
+	assert.NoError(t, err)
+	defer os.Remove(path)
+
+	_, ok := checkMinInterval(config)
+	assert.True(t, ok)
+
+	config.minInterval = 5 * time.Second
+	_, ok = checkMinInterval(config)
+	assert.True(t, ok)
+
+	// a failed run doesn't mark the interval
+	c.Sleep(time.Second)
+	_, ok = checkMinInterval(config)
+	assert.True(t, ok)
+
+	markMinInterval(path)
+	c.Sleep(time.Second)
+	_, ok = checkMinInterval(config)
+	assert.False(t, ok)
+
+	c.Sleep(5 * time.Second)
+	_, ok = checkMinInterval(config)
+	assert.True(t, ok)
+}
+
+func TestMinInterval_PathPerAccount(t *testing.T) {
+	os.Setenv("XDG_CACHE_HOME", os.TempDir())
+	defer os.Clearenv()
+
+	config := NewSwampConfig()
+	config.targetRole = "some-min-interval-role"
+	config.targetAccount = "123456789012"
+	path, _ := getMinIntervalPath(config)
+
+	config.targetAccount = "210987654321"
+	other, _ := getMinIntervalPath(config)
+	assert.NotEqual(t, path, other)
+}
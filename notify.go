@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// command showing a desktop notification on goos, empty name if there is none
+func notifyCommand(goos, title, message string) (string, []string) {
+	switch goos {
+	case "linux":
+		return "notify-send", []string{title, message}
+	case "darwin":
+		return "osascript", []string{"-e", fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))}
+	case "windows":
+		return "msg", []string{"*", title + ": " + message}
+	default:
+		return "", nil
+	}
+}
+
+func runNotifyCommand(goos, title, message string) {
+	name, args := notifyCommand(goos, title, message)
+	if name == "" {
+		return
+	}
+	if err := exec.Command(name, args...).Run(); err != nil {
+		printer.Printf("Unable to show notification: %s\n", err)
+	}
+}
+
+func isInteractive() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// An expiryWarner notifies a while before the latest credentials expire,
+// scheduling again replaces the pending notification.
+type expiryWarner struct {
+	mu     sync.Mutex
+	before time.Duration
+	timer  *time.Timer
+	notify func(title, message string)
+}
+
+func newExpiryWarner(before time.Duration, goos string) *expiryWarner {
+	return &expiryWarner{
+		before: before,
+		notify: func(title, message string) { runNotifyCommand(goos, title, message) },
+	}
+}
+
+func (w *expiryWarner) Schedule(profile string, expiration *time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if expiration == nil {
+		return
+	}
+	delay := expiration.Add(-w.before).Sub(clock.Now())
+	if delay < 0 {
+		delay = 0
+	}
+	message := fmt.Sprintf("Credentials of profile %s expire at %v", profile, expiration.Local().Format("15:04:05"))
+	w.timer = time.AfterFunc(delay, func() { w.notify("swamp", message) })
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotify_Command(t *testing.T) {
+	name, args := notifyCommand("linux", "swamp", "some message")
+	assert.Equal(t, "notify-send", name)
+	assert.Equal(t, []string{"swamp", "some message"}, args)
+
+	name, args = notifyCommand("darwin", "swamp", `some "quoted" message`)
+	assert.Equal(t, "osascript", name)
+	assert.Equal(t, []string{"-e", `display notification "some \"quoted\" message" with title "swamp"`}, args)
+
+	name, args = notifyCommand("windows", "swamp", "some message")
+	assert.Equal(t, "msg", name)
+	assert.Equal(t, []string{"*", "swamp: some message"}, args)
+
+	name, _ = notifyCommand("plan9", "swamp", "some message")
+	assert.Equal(t, "", name)
+}
+
+func TestExpiryWarner_Schedule(t *testing.T) {
+	messages := make(chan string, 1)
+	w := &expiryWarner{before: 5 * time.Minute, notify: func(title, message string) { messages <- message }}
+	expiration := time.Now().Add(time.Minute)
+
+	w.Schedule("some-profile", &expiration)
+
+	select {
+	case m := <-messages:
+		assert.Contains(t, m, "Credentials of profile some-profile expire at")
+	case <-time.After(time.Second):
+		t.Fatal("no notification")
+	}
+}
+
+func TestExpiryWarner_ScheduleReplacesPending(t *testing.T) {
+	messages := make(chan string, 2)
+	w := &expiryWarner{before: 5 * time.Minute, notify: func(title, message string) { messages <- message }}
+	expiration := time.Now().Add(time.Hour)
+
+	w.Schedule("some-profile", &expiration)
+	w.Schedule("some-profile", nil)
+
+	assert.Nil(t, w.timer)
+	assert.Len(t, messages, 0)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	OP_COMMAND  = "op"
+	OP_CATEGORY = "API Credential"
+	// error of op item get for an item missing in the vault, other errors like ambiguous titles don't create an item
+	OP_ITEM_NOT_FOUND = "isn't an item"
+)
+
+// A opField is a field of an item template of the op cli.
+type opField struct {
+	Id    string `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// A opItemTemplate is the json item template read by the op cli from stdin.
+type opItemTemplate struct {
+	Fields []opField `json:"fields"`
+}
+
+// item template of the credentials, secrets are stored as concealed fields
+func opTemplate(cred *sts.Credentials) ([]byte, error) {
+	fields := []opField{
+		{Id: "AccessKeyId", Label: "AccessKeyId", Type: "STRING", Value: *cred.AccessKeyId},
+		{Id: "SecretAccessKey", Label: "SecretAccessKey", Type: "CONCEALED", Value: *cred.SecretAccessKey},
+		{Id: "SessionToken", Label: "SessionToken", Type: "CONCEALED", Value: *cred.SessionToken},
+	}
+	if cred.Expiration != nil {
+		fields = append(fields, opField{Id: "Expiration", Label: "Expiration", Type: "STRING", Value: cred.Expiration.UTC().Format(time.RFC3339)})
+	}
+	return json.Marshal(opItemTemplate{Fields: fields})
+}
+
+func opVaultArgs(vault string) []string {
+	if vault == "" {
+		return nil
+	}
+	return []string{"--vault", vault}
+}
+
+// arguments of op item edit for an existing item, op item create otherwise,
+// the item template with the credentials is read from stdin and never shows in process listings
+func opItemArgs(exists bool, item, vault string) []string {
+	var args []string
+	if exists {
+		args = []string{"item", "edit", item}
+	} else {
+		args = []string{"item", "create", "--category", OP_CATEGORY, "--title", item}
+	}
+	return append(append(args, opVaultArgs(vault)...), "-")
+}
+
+// whether item exists in vault, failures other than a missing item are returned
+func opItemExists(item, vault string) (bool, error) {
+	output, err := exec.Command(OP_COMMAND, append([]string{"item", "get", item}, opVaultArgs(vault)...)...).CombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+	if strings.Contains(string(output), OP_ITEM_NOT_FOUND) {
+		return false, nil
+	}
+	return false, fmt.Errorf("Error reading 1password item %s: %s %s", item, err, strings.TrimSpace(string(output)))
+}
+
+// store credentials in a 1password item via the op cli, the item is created on first write
+func writeOnePassword(item, vault string, cred *sts.Credentials) error {
+	exists, err := opItemExists(item, vault)
+	if err != nil {
+		return err
+	}
+	template, err := opTemplate(cred)
+	if err != nil {
+		return err
+	}
+	c := exec.Command(OP_COMMAND, opItemArgs(exists, item, vault)...)
+	c.Stdin = bytes.NewReader(template)
+	if output, err := c.CombinedOutput(); err != nil {
+		return fmt.Errorf("Error writing 1password item %s: %s %s", item, err, strings.TrimSpace(string(output)))
+	}
+	printer.Printf("Wrote credentials to 1password item %s\n", item)
+	return nil
+}
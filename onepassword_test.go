@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake op cli in PATH, item get fails like op for the items missing and ambiguous,
+// other calls record their arguments and stdin in dir
+func fakeOpCommand(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "swamp-op-test")
+	assert.NoError(t, err)
+	script := `#!/bin/sh
+if [ "$1 $2" = "item get" ]; then
+  case "$3" in
+    missing) echo "[ERROR] \"missing\" isn't an item in the \"Private\" vault." >&2; exit 1 ;;
+    ambiguous) echo "[ERROR] More than one item matches \"ambiguous\"." >&2; exit 1 ;;
+  esac
+  exit 0
+fi
+echo "$@" > "` + dir + `/args"
+cat > "` + dir + `/stdin"
+`
+	assert.NoError(t, ioutil.WriteFile(path.Join(dir, OP_COMMAND), []byte(script), 0755))
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":/bin:/usr/bin")
+	return dir, func() {
+		os.Setenv("PATH", oldPath)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOnePassword_CreateArgs(t *testing.T) {
+	assert.Equal(t, []string{"item", "create", "--category", "API Credential", "--title", "aws-prod", "-"}, opItemArgs(false, "aws-prod", ""))
+}
+
+func TestOnePassword_EditArgs(t *testing.T) {
+	assert.Equal(t, []string{"item", "edit", "aws-prod", "--vault", "Platform", "-"}, opItemArgs(true, "aws-prod", "Platform"))
+}
+
+func TestOnePassword_Template(t *testing.T) {
+	b, err := opTemplate(testCredentials().SetExpiration(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)))
+	assert.NoError(t, err)
+
+	var template opItemTemplate
+	assert.NoError(t, json.Unmarshal(b, &template))
+	assert.Equal(t, []opField{
+		{Id: "AccessKeyId", Label: "AccessKeyId", Type: "STRING", Value: "some-access-key"},
+		{Id: "SecretAccessKey", Label: "SecretAccessKey", Type: "CONCEALED", Value: "some-secret-access-key"},
+		{Id: "SessionToken", Label: "SessionToken", Type: "CONCEALED", Value: "some-session-token"},
+		{Id: "Expiration", Label: "Expiration", Type: "STRING", Value: "2021-05-01T12:00:00Z"},
+	}, template.Fields)
+}
+
+func TestOnePassword_WriteKeepsSecretsOffCommandLine(t *testing.T) {
+	dir, cleanup := fakeOpCommand(t)
+	defer cleanup()
+
+	assert.NoError(t, writeOnePassword("missing", "", testCredentials()))
+
+	args, _ := ioutil.ReadFile(path.Join(dir, "args"))
+	stdin, _ := ioutil.ReadFile(path.Join(dir, "stdin"))
+	assert.Equal(t, "item create --category API Credential --title missing -\n", string(args))
+	assert.Contains(t, string(stdin), "some-secret-access-key")
+
+	assert.NoError(t, writeOnePassword("aws-prod", "", testCredentials()))
+	args, _ = ioutil.ReadFile(path.Join(dir, "args"))
+	assert.Equal(t, "item edit aws-prod -\n", string(args))
+}
+
+func TestOnePassword_WriteFailsOnAmbiguousItem(t *testing.T) {
+	dir, cleanup := fakeOpCommand(t)
+	defer cleanup()
+
+	err := writeOnePassword("ambiguous", "", testCredentials())
+
+	assert.EqualError(t, err, `Error reading 1password item ambiguous: exit status 1 [ERROR] More than one item matches "ambiguous".`)
+	_, err = os.Stat(path.Join(dir, "args"))
+	assert.True(t, os.IsNotExist(err))
+}
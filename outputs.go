@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// hand target credentials to all additionally configured outputs
+func writeOutputs(config *SwampConfig, cred *sts.Credentials) error {
+	if config.vaultPath != "" {
+		if err := writeVaultSecret(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"), config.vaultPath, config.vaultKvVersion, cred); err != nil {
+			return err
+		}
+	}
+	if config.keychainService != "" {
+		if err := writeKeychain(config.keychainService, config.targetProfile, cred); err != nil {
+			return err
+		}
+	}
+	if config.opItem != "" {
+		if err := writeOnePassword(config.opItem, config.opVault, cred); err != nil {
+			return err
+		}
+	}
+	if config.templateFile != "" {
+		if err := writeCredentialsTemplate(config.templateFile, config.templateOut, cred, config.region, config.targetProfile); err != nil {
+			return err
+		}
+	}
+	if config.k8sSecretOut != "" {
+		if err := writeK8sSecret(config.k8sSecretOut, config.k8sSecretName, config.k8sNamespace, config.k8sSecretKeys, cred); err != nil {
+			return err
+		}
+	}
+	if config.printEnvExport {
+		if err := writeEnvExport(os.Stdout, config.shell, config.envPrefix, config.noEnvUnset, cred, config.region); err != nil {
+			return err
+		}
+	}
+	if config.printExpiry != "" {
+		if err := writeExpiry(os.Stdout, config.printExpiry, cred.Expiration); err != nil {
+			return err
+		}
+	}
+	if config.jsonFd > 0 {
+		if err := writeJsonResult(jsonFdWriter(config.jsonFd), config, cred); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	PRINT_EXPIRY_EPOCH   = "epoch"
+	PRINT_EXPIRY_RFC3339 = "rfc3339"
+	PRINT_EXPIRY_HUMAN   = "human"
+)
+
+func formatExpiry(format string, expiration time.Time, now time.Time) string {
+	switch format {
+	case PRINT_EXPIRY_EPOCH:
+		return strconv.FormatInt(expiration.Unix(), 10)
+	case PRINT_EXPIRY_RFC3339:
+		return expiration.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%s (in %s)", expiration.Local().Format("2006-01-02 15:04:05 MST"), formatExpiresIn(&expiration, now))
+	}
+}
+
+func writeExpiry(w io.Writer, format string, expiration *time.Time) error {
+	if expiration == nil {
+		return nil
+	}
+	_, err := fmt.Fprintln(w, formatExpiry(format, *expiration, clock.Now()))
+	return err
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintExpiry_FormatExpiry(t *testing.T) {
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	expiration := time.Date(2017, 7, 6, 8, 31, 10, 0, time.UTC)
+
+	assert.Equal(t, "1499329870", formatExpiry(PRINT_EXPIRY_EPOCH, expiration, now))
+	assert.Equal(t, "2017-07-06T08:31:10Z", formatExpiry(PRINT_EXPIRY_RFC3339, expiration, now))
+	assert.Contains(t, formatExpiry(PRINT_EXPIRY_HUMAN, expiration, now), "(in 31m10s)")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// print the absolute paths of the credentials file written in format and the config file read
+func printProfilePaths(w io.Writer, format string) error {
+	credentialsPath, err := getCredentialsPath()
+	if err != nil {
+		return err
+	}
+	if format == CREDENTIALS_FORMAT_JSON {
+		credentialsPath += CREDENTIALS_JSON_SUFFIX
+	}
+	configPath, err := getSharedConfigPath()
+	if err != nil {
+		return err
+	}
+	for _, p := range []struct{ name, path string }{{"credentials", credentialsPath}, {"config", configPath}} {
+		abs, err := filepath.Abs(p.path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %s\n", p.name, abs)
+	}
+	return nil
+}
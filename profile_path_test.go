@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilePath_Print(t *testing.T) {
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", "some-dir/credentials")
+	os.Setenv("AWS_CONFIG_FILE", "/etc/aws/config")
+	defer os.Clearenv()
+	wd, _ := os.Getwd()
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, printProfilePaths(buf, CREDENTIALS_FORMAT_INI))
+	assert.Equal(t, "credentials "+filepath.Join(wd, "some-dir/credentials")+"\nconfig /etc/aws/config\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printProfilePaths(buf, CREDENTIALS_FORMAT_JSON))
+	assert.Equal(t, "credentials "+filepath.Join(wd, "some-dir/credentials.json")+"\nconfig /etc/aws/config\n", buf.String())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var profileTemplatePlaceholderPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// replace {key} placeholders of tpl by the values of vars
+func expandProfileTemplate(tpl string, vars map[string]string) string {
+	for k, v := range vars {
+		tpl = strings.Replace(tpl, "{"+k+"}", v, -1)
+	}
+	return tpl
+}
+
+// check tpl for placeholders other than the given keys
+func validateProfileTemplate(tpl string, keys ...string) error {
+	vars := map[string]string{}
+	for _, k := range keys {
+		vars[k] = ""
+	}
+	if p := profileTemplatePlaceholderPattern.FindString(expandProfileTemplate(tpl, vars)); p != "" {
+		return fmt.Errorf("Unknown placeholder %s in profile template %s", p, tpl)
+	}
+	return nil
+}
+
+// target profile name from -profile-template, resolved after assume-role
+func (config *SwampConfig) profileFromTemplate(account string) string {
+	return expandProfileTemplate(config.profileTemplate, map[string]string{
+		"account": account,
+		"role":    roleNameFromArn(*config.GetRoleArn()),
+		"region":  config.region,
+	})
+}
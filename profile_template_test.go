@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileTemplate_ProfileFromTemplate(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetRole = "arn:aws:iam::123456789012:role/team/Deploy"
+	c.region = "eu-central-1"
+	c.profileTemplate = "{account}-{role}-{region}"
+
+	assert.Equal(t, "123456789012-Deploy-eu-central-1", c.profileFromTemplate("123456789012"))
+}
+
+func TestProfileTemplate_Validate(t *testing.T) {
+	assert.NoError(t, validateProfileTemplate("{account}-{role}", "account", "role", "region"))
+	assert.Error(t, validateProfileTemplate("{account}-{team}", "account", "role", "region"))
+}
@@ -2,21 +2,36 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/go-ini/ini"
 	"github.com/golang-utils/lockfile"
 )
 
+const (
+	EXPIRATION_KEY = "x_swamp_expiration"
+)
+
 type ProfileWriter struct {
 	lock            lockfile.LockFile
 	awsPath         string
 	credentialsPath string
 	lockPath        string
+	validateWrite   bool
+	sortProfiles    bool
+	protected       []string
+	force           bool
+	backupKeep      int
+	backedUp        bool
+	combined        bool
+	managedBlock    bool
 }
 
 func NewProfileWriter() (*ProfileWriter, error) {
@@ -53,19 +68,15 @@ func (pw *ProfileWriter) WriteProfile(cred *sts.Credentials, profileName, region
 	pw.acquire_lock()
 	defer pw.release_lock()
 
-	if cfg, err := pw.getOrCreateCredentialsFile(); err != nil {
+	if err := pw.updateSection(profileName, func(sec *ini.Section) error {
+		return pw.writeSection(sec, cred, region)
+	}); err != nil {
 		return err
-	} else {
-		if sec, err := pw.getOrCreateSection(cfg, profileName); err != nil {
-			return err
-		} else {
-			if err := pw.writeSection(sec, cred, region); err != nil {
-				return err
-			}
+	}
 
-			if err := cfg.SaveTo(pw.credentialsPath); err != nil {
-				return fmt.Errorf("Error writing credentials file: %s", err)
-			}
+	if pw.validateWrite {
+		if err := pw.verifyProfile(cred, profileName); err != nil {
+			return err
 		}
 	}
 
@@ -75,12 +86,173 @@ func (pw *ProfileWriter) WriteProfile(cred *sts.Credentials, profileName, region
 	return nil
 }
 
+// expiration written to profileName by swamp, nil if unknown
+func (pw *ProfileWriter) ReadExpiration(profileName string) *time.Time {
+	cfg, err := ini.Load(pw.credentialsPath)
+	if err != nil {
+		return nil
+	}
+	sec, err := cfg.GetSection(profileName)
+	if err != nil || !sec.HasKey(EXPIRATION_KEY) {
+		return nil
+	}
+	expiration, err := time.Parse(time.RFC3339, sec.Key(EXPIRATION_KEY).String())
+	if err != nil {
+		return nil
+	}
+	return &expiration
+}
+
+// write long-lived keys, e.g. of the base profile, dropping session token and expiration
+func (pw *ProfileWriter) WriteStaticProfile(accessKeyId, secretAccessKey string, profileName *string) error {
+	pw.acquire_lock()
+	defer pw.release_lock()
+
+	if err := pw.updateSection(profileName, func(sec *ini.Section) error {
+		if err := pw.writeKey(sec, "aws_access_key_id", &accessKeyId); err != nil {
+			return err
+		}
+		if err := pw.writeKey(sec, "aws_secret_access_key", &secretAccessKey); err != nil {
+			return err
+		}
+		sec.DeleteKey("aws_session_token")
+		sec.DeleteKey(EXPIRATION_KEY)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	printer.Printf("Wrote static credentials for profile %s\n", *profileName)
+	return nil
+}
+
+// protected profiles are maintained by hand and only written with -force
+func (pw *ProfileWriter) checkProtected(profileName string) error {
+	if pw.force {
+		return nil
+	}
+	for _, p := range pw.protected {
+		if p == profileName {
+			return fmt.Errorf("Refusing to overwrite protected profile %s, use -force to overwrite it anyway", profileName)
+		}
+	}
+	return nil
+}
+
+// load credentials file, apply write to the profile's section and save it again
+func (pw *ProfileWriter) updateSection(profileName *string, write func(*ini.Section) error) error {
+	if err := pw.checkProtected(*profileName); err != nil {
+		return err
+	}
+	if pw.managedBlock {
+		return pw.updateManagedSection(profileName, write)
+	}
+	cfg, err := pw.getOrCreateCredentialsFile()
+	if err != nil {
+		return err
+	}
+	if cfg, err = pw.applyWrite(cfg, profileName, write); err != nil {
+		return err
+	}
+	return pw.saveCredentialsFile(func(w io.Writer) error {
+		_, err := cfg.WriteTo(w)
+		return err
+	})
+}
+
+// apply write to the profile's section of cfg, returns cfg or its sorted copy
+func (pw *ProfileWriter) applyWrite(cfg *ini.File, profileName *string, write func(*ini.Section) error) (*ini.File, error) {
+	sec, err := pw.getOrCreateSection(cfg, profileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := write(sec); err != nil {
+		return nil, err
+	}
+	if pw.combined {
+		if err := pw.writeConfigSection(cfg, *profileName, sec); err != nil {
+			return nil, err
+		}
+	}
+
+	if pw.sortProfiles {
+		if cfg, err = sortSections(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// back up the credentials file if configured and replace it with the output of write. The backup is
+// taken once before the first write, so the profiles written by one run don't prune the file swamp found.
+func (pw *ProfileWriter) saveCredentialsFile(write func(io.Writer) error) error {
+	if pw.backupKeep > 0 && !pw.backedUp {
+		if err := backupFile(pw.credentialsPath, clock.Now(), pw.backupKeep); err != nil {
+			return fmt.Errorf("Error backing up credentials file: %s", err)
+		}
+		pw.backedUp = true
+	}
+	if err := writeFileAtomic(pw.credentialsPath, 0600, write); err != nil {
+		return fmt.Errorf("Error writing credentials file: %s", err)
+	}
+	return nil
+}
+
+// re-read credentials file and compare profile with the written credentials
+func (pw *ProfileWriter) verifyProfile(cred *sts.Credentials, profileName *string) error {
+	cfg, err := ini.Load(pw.credentialsPath)
+	if err != nil {
+		return fmt.Errorf("Error verifying credentials file: %s", err)
+	}
+	sec, err := cfg.GetSection(*profileName)
+	if err != nil {
+		return fmt.Errorf("Error verifying credentials file: profile %s is missing", *profileName)
+	}
+	for name, value := range map[string]*string{
+		"aws_access_key_id":     cred.AccessKeyId,
+		"aws_secret_access_key": cred.SecretAccessKey,
+		"aws_session_token":     cred.SessionToken,
+	} {
+		if sec.Key(name).String() != *value {
+			return fmt.Errorf("Error verifying credentials file: %s of profile %s does not match", name, *profileName)
+		}
+	}
+	return nil
+}
+
+// copy of cfg with profiles sorted by name, the default section stays on top
+func sortSections(cfg *ini.File) (*ini.File, error) {
+	sorted := ini.Empty()
+	sections := cfg.Sections()
+	sort.SliceStable(sections, func(i, j int) bool {
+		if sections[j].Name() == ini.DefaultSection {
+			return false
+		}
+		return sections[i].Name() == ini.DefaultSection || sections[i].Name() < sections[j].Name()
+	})
+	for _, sec := range sections {
+		newSec, err := sorted.NewSection(sec.Name())
+		if err != nil {
+			return nil, fmt.Errorf("Error sorting profile %s: %s", sec.Name(), err)
+		}
+		newSec.Comment = sec.Comment
+		for _, key := range sec.Keys() {
+			newKey, err := newSec.NewKey(key.Name(), key.Value())
+			if err != nil {
+				return nil, fmt.Errorf("Error sorting profile %s: %s", sec.Name(), err)
+			}
+			newKey.Comment = key.Comment
+		}
+	}
+	return sorted, nil
+}
+
 func (pw *ProfileWriter) acquire_lock() {
 	for {
 		if err := pw.lock.Lock(pw.lockPath); err == nil {
 			return
 		} else {
-			fmt.Printf("Waiting for lock %s\n", pw.lockPath)
+			printer.Printf("Waiting for lock %s\n", pw.lockPath)
 			time.Sleep(time.Second)
 		}
 	}
@@ -90,16 +262,23 @@ func (pw *ProfileWriter) release_lock() {
 	os.Remove(pw.lockPath)
 }
 
-func (pw *ProfileWriter) getOrCreateCredentialsFile() (*ini.File, error) {
+func (pw *ProfileWriter) ensureAwsPath() error {
 	if _, err := os.Stat(pw.awsPath); err != nil {
 		if err := os.MkdirAll(pw.awsPath, os.ModePerm); err != nil {
-			return nil, fmt.Errorf("Error creating aws config path %s: %s", pw.awsPath, err)
+			return fmt.Errorf("Error creating aws config path %s: %s", pw.awsPath, err)
 		}
 	}
+	return nil
+}
+
+func (pw *ProfileWriter) getOrCreateCredentialsFile() (*ini.File, error) {
+	if err := pw.ensureAwsPath(); err != nil {
+		return nil, err
+	}
 
 	cfg, err := ini.Load(pw.credentialsPath)
 	if err != nil {
-		fmt.Printf("Unable to find credentials file %s. Creating new file.\n", pw.credentialsPath)
+		printer.Printf("Unable to find credentials file %s. Creating new file.\n", pw.credentialsPath)
 		cfg = ini.Empty()
 	}
 	return cfg, nil
@@ -115,6 +294,33 @@ func (pw *ProfileWriter) getOrCreateSection(cfg *ini.File, profileName *string)
 	return sec, err
 }
 
+// config style section of a profile in a combined file, the default profile shares its section
+func configSectionName(profileName string) string {
+	if profileName == ini.DefaultSection || profileName == "default" {
+		return profileName
+	}
+	return "profile " + profileName
+}
+
+func (pw *ProfileWriter) writeConfigSection(cfg *ini.File, profileName string, credSec *ini.Section) error {
+	sec, err := pw.getOrCreateSection(cfg, aws.String(configSectionName(profileName)))
+	if err != nil {
+		return err
+	}
+	if credSec.HasKey("region") {
+		region := credSec.Key("region").String()
+		if err := pw.writeKey(sec, "region", &region); err != nil {
+			return err
+		}
+	}
+	if !sec.HasKey("output") {
+		if _, err := sec.NewKey("output", "json"); err != nil {
+			return fmt.Errorf("Error writing config key output: %s", err)
+		}
+	}
+	return nil
+}
+
 func (pw *ProfileWriter) writeSection(sec *ini.Section, cred *sts.Credentials, region *string) error {
 	if err := pw.writeKey(sec, "aws_access_key_id", cred.AccessKeyId); err != nil {
 		return err
@@ -130,6 +336,12 @@ func (pw *ProfileWriter) writeSection(sec *ini.Section, cred *sts.Credentials, r
 			return err
 		}
 	}
+	if cred.Expiration != nil {
+		expiration := cred.Expiration.UTC().Format(time.RFC3339)
+		if err := pw.writeKey(sec, EXPIRATION_KEY, &expiration); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+)
+
+// A TokenPrompt obtains the mfa token code for a device.
+type TokenPrompt interface {
+	TokenCode(serialNumber string) (string, error)
+}
+
+// A PromptFunc is a TokenPrompt calling back into the program, e.g. showing a dialog.
+type PromptFunc func(serialNumber string) (string, error)
+
+func (f PromptFunc) TokenCode(serialNumber string) (string, error) { return f(serialNumber) }
+
+type stdinPrompt struct {
+	config *SwampConfig
+}
+
+func (p stdinPrompt) TokenCode(serialNumber string) (string, error) {
+	return askForTokenCode(p.config, serialNumber), nil
+}
+
+type execPrompt struct {
+	cmd         string
+	shell       string
+	extractCode bool
+	env         []string
+}
+
+func (p execPrompt) TokenCode(serialNumber string) (string, error) {
+	tokenCode := fetchTokenCode(serialNumber, p.cmd, p.shell, p.env)
+	if p.extractCode {
+		tokenCode = extractTokenCode(tokenCode)
+	}
+	return tokenCode, nil
+}
+
+type totpPrompt struct {
+	config *SwampConfig
+}
+
+func (p totpPrompt) TokenCode(serialNumber string) (string, error) {
+	return computeTokenCode(p.config, serialNumber), nil
+}
+
+// Prompt overriding the one selected by flags. Programs embedding swamp add a file to package main
+// setting it in an init function, e.g. to a PromptFunc showing a gui dialog.
+var tokenPrompt TokenPrompt
+
+func newTokenPrompt(config *SwampConfig) TokenPrompt {
+	switch {
+	case tokenPrompt != nil:
+		return tokenPrompt
+	case config.totpSecret != "" || config.totpSecretFile != "":
+		return totpPrompt{config}
+	case config.mfaExec != "":
+		return execPrompt{cmd: config.mfaExec, shell: config.mfaExecShell, extractCode: config.mfaExtractCode, env: config.mfaExecEnv}
+	default:
+		return stdinPrompt{config}
+	}
+}
+
+// A usedTokenCodes remembers the token code last sent to sts per device serial. Sts rejects a token code
+// used before, so get-session-token and assume-role with the same device need one each.
+type usedTokenCodes struct {
+	mu    sync.Mutex
+	codes map[string]string
+}
+
+func (c *usedTokenCodes) Used(serialNumber, code string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.codes[serialNumber] == code
+}
+
+func (c *usedTokenCodes) Put(serialNumber, code string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.codes == nil {
+		c.codes = map[string]string{}
+	}
+	c.codes[serialNumber] = code
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrompt_SelectedByFlags(t *testing.T) {
+	config := NewSwampConfig()
+	assert.IsType(t, stdinPrompt{}, newTokenPrompt(config))
+
+	config.mfaExec = "echo 123456"
+	assert.Equal(t, execPrompt{cmd: "echo 123456", shell: "/bin/sh"}, newTokenPrompt(config))
+
+	config.totpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	assert.IsType(t, totpPrompt{}, newTokenPrompt(config))
+}
+
+func TestPrompt_Override(t *testing.T) {
+	tokenPrompt = PromptFunc(func(serialNumber string) (string, error) {
+		return "654321 \n", nil
+	})
+	defer func() { tokenPrompt = nil }()
+
+	config := NewSwampConfig()
+	config.mfaExec = "echo 123456"
+
+	assert.Equal(t, "654321", getTokenCode(config, "some-device-id"))
+}
+
+func TestPrompt_FuncPassesSerial(t *testing.T) {
+	var got string
+	p := PromptFunc(func(serialNumber string) (string, error) {
+		got = serialNumber
+		return "123456", nil
+	})
+
+	code, err := p.TokenCode("some-device-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", code)
+	assert.Equal(t, "some-device-id", got)
+}
+
+func TestPrompt_UsedTokenCodes(t *testing.T) {
+	c := &usedTokenCodes{}
+
+	assert.False(t, c.Used("some-device-id", "123456"))
+
+	c.Put("some-device-id", "123456")
+	assert.True(t, c.Used("some-device-id", "123456"))
+	assert.False(t, c.Used("some-device-id", "654321"))
+	assert.False(t, c.Used("other-device-id", "123456"))
+}
+
+func TestPrompt_AsksAgainForSameDevice(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "swamp-prompt-test")
+	defer os.RemoveAll(dir)
+	calls := path.Join(dir, "calls")
+	config := NewSwampConfig()
+	config.mfaExec = "echo >> " + calls + "; echo 123456"
+	countCalls := func() int {
+		b, _ := ioutil.ReadFile(calls)
+		return len(b)
+	}
+
+	assert.Equal(t, "123456", getTokenCode(config, "some-device-id"))
+	assert.Equal(t, "123456", getTokenCode(config, "some-device-id"))
+	assert.Equal(t, 2, countCalls())
+}
+
+func TestPrompt_WaitsForNextTotpOfSameDevice(t *testing.T) {
+	fc := newFakeClock(time.Date(2017, 7, 6, 8, 0, 10, 0, time.UTC))
+	clock = fc
+	defer func() { clock = realClock{} }()
+	config := NewSwampConfig()
+	config.totpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	first := getTokenCode(config, "some-device-id")
+	second := getTokenCode(config, "some-device-id")
+
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, []time.Duration{20 * time.Second}, fc.sleeps)
+
+	getTokenCode(config, "other-device-id")
+	assert.Len(t, fc.sleeps, 1)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	PROPAGATION_RETRY_INTERVAL = 5 * time.Second
+)
+
+// check whether err may be caused by iam not having propagated a new role or trust policy yet
+func isPropagationError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == "AccessDenied" || aerr.Code() == "NoSuchEntity"
+	}
+	return false
+}
+
+// run call until it succeeds, fails for other reasons than iam propagation or timeout is used up
+func retryOnPropagation(timeout time.Duration, call func() error) error {
+	started := clock.Now()
+	for {
+		err := call()
+		if err == nil || !isPropagationError(err) {
+			return err
+		}
+		elapsed := clock.Now().Sub(started)
+		if elapsed+PROPAGATION_RETRY_INTERVAL > timeout {
+			return err
+		}
+		printer.Printf("Waiting for iam propagation, retrying after %v: %s\n", elapsed.Truncate(time.Second), err)
+		clock.Sleep(PROPAGATION_RETRY_INTERVAL)
+	}
+}
+
+// sts rejects a reused mfa token code, so assume-role with a token code is not retried
+func assumeRolePropagationTimeout(input *sts.AssumeRoleInput, timeout time.Duration) time.Duration {
+	if input.TokenCode != nil && timeout > 0 {
+		printer.Printf("Not waiting for iam propagation of %s, mfa token codes can't be reused for retries\n", *input.RoleArn)
+		return 0
+	}
+	return timeout
+}
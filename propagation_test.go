@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPropagation_IsPropagationError(t *testing.T) {
+	assert.True(t, isPropagationError(awserr.New("AccessDenied", "not authorized", nil)))
+	assert.True(t, isPropagationError(awserr.New("NoSuchEntity", "role not found", nil)))
+	assert.False(t, isPropagationError(awserr.New("ExpiredToken", "expired", nil)))
+	assert.False(t, isPropagationError(errors.New("some error")))
+}
+
+func TestPropagation_RetriesUntilSuccess(t *testing.T) {
+	c := newFakeClock(time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC))
+	clock = c
+	defer func() { clock = realClock{} }()
+	calls := 0
+
+	err := retryOnPropagation(time.Minute, func() error {
+		if calls++; calls < 3 {
+			return awserr.New("AccessDenied", "not authorized", nil)
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{PROPAGATION_RETRY_INTERVAL, PROPAGATION_RETRY_INTERVAL}, c.sleeps)
+}
+
+func TestPropagation_GivesUpAfterTimeout(t *testing.T) {
+	c := newFakeClock(time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC))
+	clock = c
+	defer func() { clock = realClock{} }()
+	calls := 0
+
+	err := retryOnPropagation(12*time.Second, func() error {
+		calls++
+		return awserr.New("NoSuchEntity", "role not found", nil)
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestPropagation_NoRetryWithoutTimeoutOrOnOtherErrors(t *testing.T) {
+	calls := 0
+	retryOnPropagation(0, func() error {
+		calls++
+		return awserr.New("AccessDenied", "not authorized", nil)
+	})
+	retryOnPropagation(time.Minute, func() error {
+		calls++
+		return errors.New("some error")
+	})
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestPropagation_NoRetriesWithTokenCode(t *testing.T) {
+	input := &sts.AssumeRoleInput{RoleArn: aws.String("arn:aws:iam::123456789012:role/some-role")}
+	assert.Equal(t, time.Minute, assumeRolePropagationTimeout(input, time.Minute))
+
+	input.TokenCode = aws.String("123456")
+	assert.Equal(t, time.Duration(0), assumeRolePropagationTimeout(input, time.Minute))
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	EC2_METADATA_TIMEOUT = 300 * time.Millisecond
+	REGION_TYPO_DISTANCE = 2
+)
+
+// query the instance metadata service for the current region, returns "" when not running on ec2
+func detectEc2Region() string {
+	sess, err := session.NewSession()
+	if err != nil {
+		return ""
+	}
+	client := ec2metadata.New(sess, &aws.Config{
+		HTTPClient: &http.Client{Timeout: EC2_METADATA_TIMEOUT},
+		MaxRetries: aws.Int(0),
+	})
+	region, err := client.Region()
+	if err != nil {
+		return ""
+	}
+	return region
+}
+
+// region given with -region, AWS_REGION or AWS_DEFAULT_REGION
+func hasExplicitRegion(config *SwampConfig) bool {
+	return config.region != "" || os.Getenv("AWS_REGION") != "" || os.Getenv("AWS_DEFAULT_REGION") != ""
+}
+
+// regions of target profiles from regionMap of config files, overridden by -region-map
+func (config *SwampConfig) GetProfileRegions() (map[string]string, error) {
+	regions := map[string]string{}
+	for profile, region := range config.profileRegions {
+		regions[profile] = region
+	}
+	for _, m := range config.regionMap {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid region map %s, expected profile=region", m)
+		}
+		regions[parts[0]] = parts[1]
+	}
+	return regions, nil
+}
+
+// region written to profile, fallback unless it's mapped to its own region.
+// The region of the sts endpoint stays the same.
+func (config *SwampConfig) profileRegion(profile string, fallback *string) *string {
+	regions, _ := config.GetProfileRegions()
+	if region, ok := regions[profile]; ok {
+		return &region
+	}
+	return fallback
+}
+
+// region set for profile in the shared config file, "" if none is set
+func sharedConfigRegion(profile string) string {
+	sess, err := session.NewSessionWithOptions(session.Options{Profile: profile, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return ""
+	}
+	return aws.StringValue(sess.Config.Region)
+}
+
+// fall back to the instance's region if no region is given explicitly or set in the base profile,
+// so the instance metadata service is only probed when nothing else sets the region
+func resolveRegion(config *SwampConfig, detect func() string) {
+	if hasExplicitRegion(config) || sharedConfigRegion(config.profile) != "" {
+		return
+	}
+	if region := detect(); region != "" {
+		printer.Printf("Using region %s from instance metadata\n", region)
+		config.region = region
+	}
+}
+
+// all regions known to the sdk, sorted by name
+func knownRegions() []string {
+	var regions []string
+	for _, p := range endpoints.DefaultPartitions() {
+		for id := range p.Regions() {
+			regions = append(regions, id)
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// print the regions the sdk knows for partition with their description, sorted by name
+func listRegions(w io.Writer, partition string) error {
+	var ids []string
+	for _, p := range endpoints.DefaultPartitions() {
+		if p.ID() != partition {
+			ids = append(ids, p.ID())
+			continue
+		}
+		regions := p.Regions()
+		var names []string
+		for id := range regions {
+			names = append(names, id)
+		}
+		sort.Strings(names)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			fmt.Fprintf(tw, "%s\t%s\n", name, regions[name].Description())
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("Unknown partition %s, use one of %s", partition, strings.Join(ids, ", "))
+}
+
+// check region against the known regions, suggest the closest one on typos
+func validateRegion(region string) error {
+	best, bestDistance := "", REGION_TYPO_DISTANCE+1
+	for _, r := range knownRegions() {
+		if r == region {
+			return nil
+		}
+		if d := levenshtein(region, r); d < bestDistance {
+			best, bestDistance = r, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("Unknown region %s, did you mean %s?", region, best)
+	}
+	return fmt.Errorf("Unknown region %s", region)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
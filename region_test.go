@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegion_ResolveRegionKeepsExplicitRegion(t *testing.T) {
+	config := NewSwampConfig()
+	config.region = "eu-central-1"
+
+	resolveRegion(config, func() string { return "us-east-1" })
+
+	assert.Equal(t, "eu-central-1", config.region)
+}
+
+func TestRegion_ResolveRegionFromMetadata(t *testing.T) {
+	os.Setenv("AWS_CONFIG_FILE", "does-not-exist")
+	defer os.Unsetenv("AWS_CONFIG_FILE")
+	config := NewSwampConfig()
+
+	resolveRegion(config, func() string { return "us-east-1" })
+
+	assert.Equal(t, "us-east-1", config.region)
+}
+
+func TestRegion_ResolveRegionOffInstance(t *testing.T) {
+	os.Setenv("AWS_CONFIG_FILE", "does-not-exist")
+	defer os.Unsetenv("AWS_CONFIG_FILE")
+	config := NewSwampConfig()
+
+	resolveRegion(config, func() string { return "" })
+
+	assert.Equal(t, "", config.region)
+}
+
+func TestRegion_ResolveRegionKeepsProfileRegion(t *testing.T) {
+	configPath := path.Join(os.TempDir(), "swamp-region-test-config")
+	ioutil.WriteFile(configPath, []byte("[profile some-profile]\nregion = eu-west-1\n"), 0600)
+	defer os.Remove(configPath)
+	os.Setenv("AWS_CONFIG_FILE", configPath)
+	defer os.Unsetenv("AWS_CONFIG_FILE")
+	config := NewSwampConfig()
+	config.profile = "some-profile"
+
+	resolveRegion(config, func() string {
+		t.Error("instance metadata must not be queried")
+		return "us-east-1"
+	})
+
+	assert.Equal(t, "", config.region)
+}
+
+func TestRegion_ValidateRegion(t *testing.T) {
+	assert.NoError(t, validateRegion("eu-central-1"))
+	assert.NoError(t, validateRegion("us-gov-west-1"))
+	assert.EqualError(t, validateRegion("us-east1"), "Unknown region us-east1, did you mean us-east-1?")
+	assert.EqualError(t, validateRegion("moon-base-1"), "Unknown region moon-base-1")
+}
+
+func TestRegion_ValidateRegionInConfig(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.region = "us-east1"
+
+	assert.Error(t, c.Validate())
+
+	c.ignoreInvalidRegion = true
+	assert.NoError(t, c.Validate())
+}
+
+func TestRegion_ListRegions(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	assert.NoError(t, listRegions(buf, "aws"))
+	assert.Contains(t, buf.String(), "eu-central-1    Europe (Frankfurt)\n")
+	assert.NotContains(t, buf.String(), "cn-north-1")
+
+	buf.Reset()
+	assert.NoError(t, listRegions(buf, "aws-cn"))
+	assert.Contains(t, buf.String(), "cn-north-1")
+}
+
+func TestRegion_ListRegionsUnknownPartition(t *testing.T) {
+	assert.Error(t, listRegions(new(bytes.Buffer), "aws-mars"))
+}
+
+func TestRegion_ProfileRegion(t *testing.T) {
+	c := NewSwampConfig()
+	c.profileRegions = map[string]string{"team3-live": "us-east-1", "team4-live": "eu-west-1"}
+	c.regionMap = []string{"team4-live=ap-southeast-2"}
+	fallback := aws.String("eu-central-1")
+
+	assert.Equal(t, "us-east-1", *c.profileRegion("team3-live", fallback))
+	assert.Equal(t, "ap-southeast-2", *c.profileRegion("team4-live", fallback))
+	assert.Equal(t, fallback, c.profileRegion("team5-live", fallback))
+}
+
+func TestRegion_ValidateRegionMap(t *testing.T) {
+	c := NewSwampConfig()
+	c.targetAccount = "1234567890"
+	c.targetRole = "some-role"
+	c.regionMap = []string{"team4-live"}
+
+	assert.EqualError(t, c.Validate(), "Invalid region map team4-live, expected profile=region")
+
+	c.regionMap = []string{"team4-live=eu-west-1"}
+	assert.NoError(t, c.Validate())
+
+	c.profileRegions = map[string]string{"team3-live": "eu-wset-1"}
+	assert.Error(t, c.Validate())
+}
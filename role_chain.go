@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"gopkg.in/yaml.v2"
+)
+
+// A chainHop is one assume-role of -assume-role-chain-file.
+type chainHop struct {
+	RoleArn     string            `yaml:"roleArn"`
+	ExternalId  string            `yaml:"externalId"`
+	SessionName string            `yaml:"sessionName"`
+	Duration    int64             `yaml:"duration"`
+	Tags        map[string]string `yaml:"tags"`
+	// ExternalId was read from the environment and is masked in printed commands
+	externalIdFromEnv bool
+}
+
+type roleChain struct {
+	Hops []chainHop `yaml:"hops"`
+}
+
+var (
+	sessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+	externalIdPattern  = regexp.MustCompile(`^[\w+=,.@:/-]+$`)
+)
+
+// external ids of the form env:NAME are resolved
+func (hop *chainHop) validate() error {
+	if !roleArnPattern.MatchString(hop.RoleArn) || strings.Contains(hop.RoleArn, "{") {
+		return fmt.Errorf("Invalid role arn: %s", hop.RoleArn)
+	}
+	if hop.ExternalId != "" {
+		hop.externalIdFromEnv = strings.HasPrefix(hop.ExternalId, EXTERNAL_ID_ENV_PREFIX)
+		externalId, err := resolveExternalId(hop.ExternalId)
+		if err != nil {
+			return fmt.Errorf("Invalid external id of %s: %s", hop.RoleArn, err)
+		}
+		hop.ExternalId = externalId
+	}
+	if hop.SessionName != "" && !sessionNamePattern.MatchString(hop.SessionName) {
+		return fmt.Errorf("Invalid session name of %s: %s", hop.RoleArn, hop.SessionName)
+	}
+	if hop.Duration != 0 && (hop.Duration < 900 || hop.Duration > 43200) {
+		return fmt.Errorf("Invalid duration of %s: %d, must be between 900 and 43200 seconds", hop.RoleArn, hop.Duration)
+	}
+	return validateSessionTags(hop.Tags)
+}
+
+func loadRoleChain(path string) (*roleChain, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &roleChain{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("Error parsing assume role chain file %s: %s", path, err)
+	}
+	if len(c.Hops) == 0 {
+		return nil, fmt.Errorf("Assume role chain file %s has no hops", path)
+	}
+	for i := range c.Hops {
+		if err := c.Hops[i].validate(); err != nil {
+			return nil, fmt.Errorf("Error in hop %d of assume role chain file %s: %s", i+1, path, err)
+		}
+	}
+	return c, nil
+}
+
+// load -assume-role-chain-file, its last hop becomes the target role
+func (config *SwampConfig) LoadRoleChain() error {
+	if config.roleChainFile == "" {
+		return nil
+	}
+	if config.targetRole != "" {
+		return errors.New("Target role and assume role chain file are mutual exclusive")
+	}
+	c, err := loadRoleChain(config.roleChainFile)
+	if err != nil {
+		return err
+	}
+	config.roleChain = c.Hops
+	last := c.Hops[len(c.Hops)-1]
+	config.targetRole = last.RoleArn
+	if last.Duration != 0 {
+		config.targetDuration = last.Duration
+	}
+	return nil
+}
+
+// set the hop's fields on input, tags are added to the ones given on command line
+// and the duration of the last hop is already the target duration
+func (hop chainHop) apply(input *sts.AssumeRoleInput) {
+	input.RoleArn = aws.String(hop.RoleArn)
+	if hop.ExternalId != "" {
+		input.ExternalId = aws.String(hop.ExternalId)
+	}
+	if hop.SessionName != "" {
+		input.RoleSessionName = aws.String(hop.SessionName)
+	}
+	if hop.Duration != 0 && input.DurationSeconds == nil {
+		input.DurationSeconds = aws.Int64(hop.Duration)
+	}
+	if len(hop.Tags) > 0 {
+		input.Tags = append(input.Tags, toStsTags(hop.Tags)...)
+	}
+}
+
+// assume all hops one after another, returns a session with the credentials of the last hop,
+// chaining tells whether sess already holds credentials of an assumed role
+func assumeChainHops(sess *session.Session, hops []chainHop, roleSessionName string, chaining bool) *session.Session {
+	for i, hop := range hops {
+		input := &sts.AssumeRoleInput{RoleSessionName: aws.String(roleSessionName)}
+		hop.apply(input)
+		if (chaining || i > 0) && input.DurationSeconds != nil {
+			input.DurationSeconds = aws.Int64(clampChainedDuration(hop.RoleArn, *input.DurationSeconds))
+		}
+		cred := assumeRole(sts.New(sess), input)
+		printer.Printf("Assumed role %s\n", hop.RoleArn)
+		sess = sess.Copy(&aws.Config{Credentials: credentials.NewStaticCredentials(*cred.AccessKeyId, *cred.SecretAccessKey, *cred.SessionToken)})
+	}
+	return sess
+}
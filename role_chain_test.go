@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleChain_Load(t *testing.T) {
+	c, err := loadRoleChain("example/role-chain.yaml")
+
+	assert.NoError(t, err)
+	assert.Len(t, c.Hops, 3)
+	assert.Equal(t, "some-external-id", c.Hops[1].ExternalId)
+	assert.Equal(t, "platform", c.Hops[1].Tags["team"])
+}
+
+func TestRoleChain_LoadInvalidHop(t *testing.T) {
+	chainFile := path.Join(os.TempDir(), "swamp-role-chain-test.yaml")
+	defer os.Remove(chainFile)
+
+	for _, hop := range []string{
+		"roleArn: some-role",
+		"roleArn: arn:aws:iam::{account}:role/some-role",
+		"roleArn: arn:aws:iam::111111111111:role/some-role\n    duration: 60",
+		"roleArn: arn:aws:iam::111111111111:role/some-role\n    sessionName: some session",
+		"roleArn: arn:aws:iam::111111111111:role/some-role\n    unknown: field",
+	} {
+		ioutil.WriteFile(chainFile, []byte("hops:\n  - "+hop+"\n"), 0644)
+		_, err := loadRoleChain(chainFile)
+		assert.Error(t, err, hop)
+	}
+
+	ioutil.WriteFile(chainFile, []byte("hops: []\n"), 0644)
+	_, err := loadRoleChain(chainFile)
+	assert.Error(t, err)
+}
+
+func TestRoleChain_LoadRoleChainSetsTargetRole(t *testing.T) {
+	c := NewSwampConfig()
+	c.roleChainFile = "example/role-chain.yaml"
+
+	assert.NoError(t, c.LoadRoleChain())
+	assert.Equal(t, "arn:aws:iam::333333333333:role/deploy", c.targetRole)
+	assert.Equal(t, int64(3600), c.targetDuration)
+	assert.NoError(t, c.Validate())
+
+	c.roleChain = nil
+	assert.Error(t, c.LoadRoleChain())
+}
+
+func TestRoleChain_Apply(t *testing.T) {
+	hop := chainHop{
+		RoleArn:     "arn:aws:iam::222222222222:role/partner-access",
+		ExternalId:  "some-external-id",
+		SessionName: "some-session",
+		Duration:    1800,
+		Tags:        map[string]string{"team": "platform"},
+	}
+	input := &sts.AssumeRoleInput{
+		RoleSessionName: aws.String("some-user"),
+		Tags:            toStsTags(map[string]string{"env": "prod"}),
+	}
+
+	hop.apply(input)
+
+	assert.Equal(t, "arn:aws:iam::222222222222:role/partner-access", *input.RoleArn)
+	assert.Equal(t, "some-external-id", *input.ExternalId)
+	assert.Equal(t, "some-session", *input.RoleSessionName)
+	assert.Equal(t, int64(1800), *input.DurationSeconds)
+	assert.Len(t, input.Tags, 2)
+
+	input.DurationSeconds = aws.Int64(900)
+	hop.apply(input)
+	assert.Equal(t, int64(900), *input.DurationSeconds)
+}
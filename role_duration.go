@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+const (
+	MAX_DURATION_CACHE_TTL = 24 * time.Hour
+	MAX_CHAINED_DURATION   = int64(60 * 60)
+)
+
+type maxDurationCacheEntry struct {
+	MaxSessionDuration int64     `json:"maxSessionDuration"`
+	Fetched            time.Time `json:"fetched"`
+}
+
+// A maxDurationCache maps role arns to their max session duration on disk.
+type maxDurationCache struct {
+	path    string
+	entries map[string]maxDurationCacheEntry
+}
+
+func getMaxDurationCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "swamp", "max-session-duration.json"), nil
+}
+
+// load cache from path, a missing or broken cache file results in an empty cache
+func loadMaxDurationCache(path string) *maxDurationCache {
+	c := &maxDurationCache{path: path, entries: map[string]maxDurationCacheEntry{}}
+	if b, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(b, &c.entries)
+	}
+	return c
+}
+
+func (c *maxDurationCache) Get(roleArn string, now time.Time) (int64, bool) {
+	e, ok := c.entries[roleArn]
+	if !ok || now.Sub(e.Fetched) > MAX_DURATION_CACHE_TTL {
+		return 0, false
+	}
+	return e.MaxSessionDuration, true
+}
+
+func (c *maxDurationCache) Put(roleArn string, maxSessionDuration int64, now time.Time) {
+	c.entries[roleArn] = maxDurationCacheEntry{MaxSessionDuration: maxSessionDuration, Fetched: now}
+}
+
+func (c *maxDurationCache) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, b, 0600)
+}
+
+// role name as expected by iam, i.e. without path
+func roleNameFromArn(roleArn string) string {
+	parts := strings.Split(roleArn, "/")
+	return parts[len(parts)-1]
+}
+
+// account id of an arn, "" if it has none
+func arnAccount(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+func fetchMaxSessionDuration(sess *session.Session, roleArn string) (int64, error) {
+	output, err := iam.New(sess).GetRole(&iam.GetRoleInput{RoleName: aws.String(roleNameFromArn(roleArn))})
+	if err != nil {
+		return 0, err
+	}
+	return *output.Role.MaxSessionDuration, nil
+}
+
+// look up the role's max session duration, cached for a day. Iam of the session's account only knows roles
+// of that account, a role of another account can't be looked up.
+func getMaxSessionDuration(sess *session.Session, sessionAccount, roleArn string) (int64, error) {
+	if account := arnAccount(roleArn); account != sessionAccount {
+		return 0, fmt.Errorf("role is in account %s, but iam:GetRole runs in account %s", account, sessionAccount)
+	}
+	path, err := getMaxDurationCachePath()
+	if err != nil {
+		return fetchMaxSessionDuration(sess, roleArn)
+	}
+	cache := loadMaxDurationCache(path)
+	if d, ok := cache.Get(roleArn, clock.Now()); ok {
+		return d, nil
+	}
+	d, err := fetchMaxSessionDuration(sess, roleArn)
+	if err != nil {
+		return 0, err
+	}
+	cache.Put(roleArn, d, clock.Now())
+	if err := cache.Save(); err != nil {
+		printer.Printf("Unable to write max session duration cache %s: %s\n", path, err)
+	}
+	return d, nil
+}
+
+// clamp the requested duration to the role's max session duration, keep it if the max is unknown
+func clampTargetDuration(sess *session.Session, sessionAccount, roleArn string, duration int64) int64 {
+	max, err := getMaxSessionDuration(sess, sessionAccount, roleArn)
+	if err != nil {
+		printer.Printf("Unable to look up max session duration of %s: %s\n", roleArn, err)
+		return duration
+	}
+	if duration > max {
+		printer.Printf("Clamping target duration of %d seconds to the max session duration of %s, %d seconds\n", duration, roleArn, max)
+		return max
+	}
+	return duration
+}
+
+// the role's max session duration, fallback if iam can't be read
+func maxTargetDuration(sess *session.Session, sessionAccount, roleArn string, fallback int64) int64 {
+	max, err := getMaxSessionDuration(sess, sessionAccount, roleArn)
+	if err != nil {
+		printer.Printf("Unable to look up max session duration of %s, using %d seconds: %s\n", roleArn, fallback, err)
+		return fallback
+	}
+	printer.Printf("Using max session duration of %d seconds\n", max)
+	return max
+}
+
+// whether the caller is an assumed role rather than a user or a federated session
+func isAssumedRole(callerArn string) bool {
+	return strings.Contains(callerArn, ":assumed-role/")
+}
+
+// sts allows at most one hour for assume-role with credentials of an assumed role
+func clampChainedDuration(roleArn string, duration int64) int64 {
+	if duration <= MAX_CHAINED_DURATION {
+		return duration
+	}
+	printer.Printf("Clamping duration of %s to %d seconds, sts allows at most one hour when chaining roles\n", roleArn, MAX_CHAINED_DURATION)
+	return MAX_CHAINED_DURATION
+}
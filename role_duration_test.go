@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleDuration_RoleNameFromArn(t *testing.T) {
+	assert.Equal(t, "some-role", roleNameFromArn("arn:aws:iam::1234567890:role/some-role"))
+	assert.Equal(t, "Developer", roleNameFromArn("arn:aws:iam::1234567890:role/users/Developer"))
+}
+
+func TestRoleDuration_ArnAccount(t *testing.T) {
+	assert.Equal(t, "123456789012", arnAccount("arn:aws:iam::123456789012:role/users/Developer"))
+	assert.Equal(t, "", arnAccount("some-role"))
+}
+
+func TestRoleDuration_CrossAccountRoleNotLookedUp(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer server.Close()
+	sess := session.Must(session.NewSession(&aws.Config{Endpoint: aws.String(server.URL), Region: aws.String("eu-central-1"),
+		Credentials: credentials.NewStaticCredentials("some-access-key", "some-secret-key", "")}))
+	roleArn := "arn:aws:iam::210987654321:role/admin"
+
+	_, err := getMaxSessionDuration(sess, "123456789012", roleArn)
+	assert.EqualError(t, err, "role is in account 210987654321, but iam:GetRole runs in account 123456789012")
+	assert.Equal(t, int64(43200), maxTargetDuration(sess, "123456789012", roleArn, 43200))
+	assert.Equal(t, int64(7200), clampTargetDuration(sess, "123456789012", roleArn, 7200))
+	assert.Equal(t, 0, requests)
+}
+
+func TestRoleDuration_CacheRoundTrip(t *testing.T) {
+	cachePath := path.Join(os.TempDir(), "swamp-test-cache", "max-session-duration.json")
+	defer os.RemoveAll(path.Dir(cachePath))
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+
+	c := loadMaxDurationCache(cachePath)
+	c.Put("some-arn", 7200, now)
+	assert.NoError(t, c.Save())
+
+	d, ok := loadMaxDurationCache(cachePath).Get("some-arn", now.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, int64(7200), d)
+}
+
+func TestRoleDuration_CacheExpires(t *testing.T) {
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+
+	c := loadMaxDurationCache("does-not-exist")
+	c.Put("some-arn", 7200, now)
+
+	_, ok := c.Get("some-arn", now.Add(25*time.Hour))
+	assert.False(t, ok)
+	_, ok = c.Get("other-arn", now)
+	assert.False(t, ok)
+}
+
+func TestRoleDuration_IsAssumedRole(t *testing.T) {
+	assert.True(t, isAssumedRole("arn:aws:sts::123456789012:assumed-role/admin/john.doe"))
+	assert.False(t, isAssumedRole("arn:aws:iam::123456789012:user/john.doe"))
+	assert.False(t, isAssumedRole("arn:aws:sts::123456789012:federated-user/john.doe"))
+}
+
+func TestRoleDuration_ClampChainedDuration(t *testing.T) {
+	assert.Equal(t, int64(900), clampChainedDuration("arn:aws:iam::123456789012:role/admin", 900))
+	assert.Equal(t, int64(3600), clampChainedDuration("arn:aws:iam::123456789012:role/admin", 3600))
+	assert.Equal(t, int64(3600), clampChainedDuration("arn:aws:iam::123456789012:role/admin", 43200))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+)
+
+const (
+	MAX_SESSION_NAME_LEN = 64
+	MIN_SESSION_NAME_LEN = 2
+	sessionNameHashLen   = 8
+)
+
+var invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// strip chars not allowed by sts and truncate to maxLen, optionally replacing the overflow with a short hash
+func sanitizeSessionName(name string, maxLen int, hashOverflow bool) string {
+	name = invalidSessionNameChars.ReplaceAllString(name, "")
+	for len(name) < MIN_SESSION_NAME_LEN {
+		name += "-"
+	}
+	if len(name) <= maxLen {
+		return name
+	}
+	if !hashOverflow || maxLen <= sessionNameHashLen+1 {
+		return name[:maxLen]
+	}
+	sum := sha1.Sum([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:sessionNameHashLen]
+	return name[:maxLen-sessionNameHashLen-1] + "-" + hash
+}
+
+// session name of the caller with -reason appended, only the caller part is truncated
+func buildSessionName(caller, reason string, maxLen int, hashOverflow bool) string {
+	if reason == "" {
+		return sanitizeSessionName(caller, maxLen, hashOverflow)
+	}
+	return sanitizeSessionName(caller, maxLen-len(reason)-1, hashOverflow) + "-" + reason
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeSessionName_Valid(t *testing.T) {
+	assert.Equal(t, "john.doe@example.com", sanitizeSessionName("john.doe@example.com", 64, false))
+}
+
+func TestSanitizeSessionName_StripsInvalidChars(t *testing.T) {
+	assert.Equal(t, "JohnDoe,Team=a", sanitizeSessionName("John Doe:, Team=a!", 64, false))
+}
+
+func TestSanitizeSessionName_PadsShortName(t *testing.T) {
+	assert.Equal(t, "a-", sanitizeSessionName("a", 64, false))
+	assert.Equal(t, "--", sanitizeSessionName("ä", 64, false))
+}
+
+func TestSanitizeSessionName_Truncates(t *testing.T) {
+	name := strings.Repeat("a", 70)
+	assert.Equal(t, strings.Repeat("a", 64), sanitizeSessionName(name, 64, false))
+	assert.Equal(t, strings.Repeat("a", 32), sanitizeSessionName(name, 32, false))
+}
+
+func TestSanitizeSessionName_HashesOverflow(t *testing.T) {
+	a := sanitizeSessionName(strings.Repeat("a", 64)+"-one", 64, true)
+	b := sanitizeSessionName(strings.Repeat("a", 64)+"-two", 64, true)
+
+	assert.Len(t, a, 64)
+	assert.Len(t, b, 64)
+	assert.True(t, strings.HasPrefix(a, strings.Repeat("a", 55)+"-"))
+	assert.NotEqual(t, a, b)
+	assert.True(t, sessionNamePattern.MatchString(a))
+}
+
+func TestSanitizeSessionName_HashesOnlyOverflow(t *testing.T) {
+	assert.Equal(t, "short", sanitizeSessionName("short", 64, true))
+}
+
+func TestSanitizeSessionName_WithReason(t *testing.T) {
+	assert.Equal(t, "john.doe-TICKET-123", buildSessionName("john.doe", "TICKET-123", 64, false))
+	assert.Equal(t, "john.doe", buildSessionName("john.doe", "", 64, false))
+
+	name := buildSessionName(strings.Repeat("a", 70), "TICKET-123", 64, false)
+	assert.Len(t, name, 64)
+	assert.True(t, strings.HasSuffix(name, "a-TICKET-123"))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	MAX_POLICY_ARNS = 10
+	MAX_POLICY_SIZE = 2048
+	POLICY_STDIN    = "-"
+	// warn from this percentage of the packed policy size limit reported by sts
+	PACKED_POLICY_SIZE_WARNING = 90
+)
+
+var policyArnPattern = regexp.MustCompile(`^arn:(aws|aws-cn|aws-us-gov):iam::(\d{12}|aws):policy/.+`)
+
+func validatePolicyArns(policyArns []string) error {
+	if len(policyArns) > MAX_POLICY_ARNS {
+		return fmt.Errorf("Too many policy arns: %d, at most %d are allowed", len(policyArns), MAX_POLICY_ARNS)
+	}
+	for _, arn := range policyArns {
+		if !policyArnPattern.MatchString(arn) {
+			return fmt.Errorf("Invalid policy arn: %s", arn)
+		}
+	}
+	return nil
+}
+
+// sts limits the plaintext of the inline policy and the managed policy arns together
+func sessionPolicySize(policy string, policyArns []string) int {
+	size := utf8.RuneCountInString(policy)
+	for _, arn := range policyArns {
+		size += utf8.RuneCountInString(arn)
+	}
+	return size
+}
+
+func validateSessionPolicySize(policy string, policyArns []string) error {
+	if size := sessionPolicySize(policy, policyArns); size > MAX_POLICY_SIZE {
+		return fmt.Errorf("Session policies have %d characters, sts allows at most %d for the inline policy and policy arns together", size, MAX_POLICY_SIZE)
+	}
+	return nil
+}
+
+// sts reports the packed size of session policies in percent of the limit, warn when getting close
+func warnOnPackedPolicySize(packedPolicySize *int64) {
+	if packedPolicySize != nil && *packedPolicySize >= PACKED_POLICY_SIZE_WARNING {
+		printer.Printf("Warning: session policies use %d%% of the packed size limit of sts\n", *packedPolicySize)
+	}
+}
+
+func toStsPolicyArns(policyArns []string) []*sts.PolicyDescriptorType {
+	var ret []*sts.PolicyDescriptorType
+	for _, arn := range policyArns {
+		ret = append(ret, &sts.PolicyDescriptorType{Arn: aws.String(arn)})
+	}
+	return ret
+}
+
+// check whether err is caused by session policies exceeding the packed size limit
+func isPackedPolicyTooLarge(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == sts.ErrCodePackedPolicyTooLargeException
+	}
+	return false
+}
+
+// read the inline session policy from path, - reads it from stdin, the json is compacted
+func readSessionPolicy(path string, stdin io.Reader) (string, error) {
+	var b []byte
+	var err error
+	if path == POLICY_STDIN {
+		b, err = ioutil.ReadAll(stdin)
+	} else {
+		b, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	compacted := new(bytes.Buffer)
+	if err := json.Compact(compacted, b); err != nil {
+		return "", fmt.Errorf("Session policy is no valid json: %s", err)
+	}
+	return compacted.String(), nil
+}
+
+// load the inline session policy of -policy
+func (config *SwampConfig) LoadSessionPolicy() error {
+	if config.policyFile == "" {
+		return nil
+	}
+	policy, err := readSessionPolicy(config.policyFile, os.Stdin)
+	if err != nil {
+		return err
+	}
+	config.policy = policy
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionPolicy_ValidatePolicyArns(t *testing.T) {
+	assert.NoError(t, validatePolicyArns([]string{
+		"arn:aws:iam::aws:policy/ReadOnlyAccess",
+		"arn:aws-us-gov:iam::123456789012:policy/some/path/some-policy",
+	}))
+	assert.Error(t, validatePolicyArns([]string{"ReadOnlyAccess"}))
+	assert.Error(t, validatePolicyArns([]string{"arn:aws:iam::123456789012:role/some-role"}))
+}
+
+func TestSessionPolicy_ValidateTooManyPolicyArns(t *testing.T) {
+	var arns []string
+	for i := 0; i <= MAX_POLICY_ARNS; i++ {
+		arns = append(arns, "arn:aws:iam::aws:policy/ReadOnlyAccess")
+	}
+
+	assert.Error(t, validatePolicyArns(arns))
+}
+
+func TestSessionPolicy_IsPackedPolicyTooLarge(t *testing.T) {
+	assert.True(t, isPackedPolicyTooLarge(awserr.New("PackedPolicyTooLarge", "Packed policy too large", nil)))
+	assert.False(t, isPackedPolicyTooLarge(errors.New("some error")))
+}
+
+func TestSessionPolicy_ReadFromStdin(t *testing.T) {
+	policy, err := readSessionPolicy("-", strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}]
+}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`, policy)
+}
+
+func TestSessionPolicy_ReadInvalid(t *testing.T) {
+	_, err := readSessionPolicy("-", strings.NewReader(`{"Version": `))
+	assert.Error(t, err)
+
+	_, err = readSessionPolicy("does-not-exists", nil)
+	assert.Error(t, err)
+}
+
+func TestSessionPolicy_ValidateSize(t *testing.T) {
+	arn := "arn:aws:iam::aws:policy/ReadOnlyAccess"
+	policy := `{"Statement":[{"Effect":"Deny","Action":"s3:DeleteObject","Resource":"arn:aws:s3:::bücket/*"}]}`
+
+	assert.Equal(t, 95+len(arn), sessionPolicySize(policy, []string{arn}))
+	assert.NoError(t, validateSessionPolicySize(policy, []string{arn}))
+
+	policy = strings.Repeat("x", MAX_POLICY_SIZE-len(arn)+1)
+	assert.EqualError(t, validateSessionPolicySize(policy, []string{arn}), "Session policies have 2049 characters, sts allows at most 2048 for the inline policy and policy arns together")
+}
+
+func TestSessionPolicy_WarnOnPackedPolicySize(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printer.SetOutput(buf)
+	defer printer.SetOutput(os.Stdout)
+
+	warnOnPackedPolicySize(nil)
+	warnOnPackedPolicySize(aws.Int64(42))
+	assert.Empty(t, buf.String())
+
+	warnOnPackedPolicySize(aws.Int64(93))
+	assert.Equal(t, "Warning: session policies use 93% of the packed size limit of sts\n", buf.String())
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	MAX_SESSION_TAGS             = 50
+	MAX_SESSION_TAG_KEY_LENGTH   = 128
+	MAX_SESSION_TAG_VALUE_LENGTH = 256
+)
+
+func readSessionTagsFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	if err := json.Unmarshal(b, &tags); err != nil {
+		return nil, fmt.Errorf("Error parsing session tags file %s: %s", path, err)
+	}
+	return tags, nil
+}
+
+// session tags from -session-tags-file, overridden by -session-tag
+func (config *SwampConfig) GetSessionTags() (map[string]string, error) {
+	tags := map[string]string{}
+	if config.sessionTagsFile != "" {
+		var err error
+		if tags, err = readSessionTagsFile(config.sessionTagsFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range config.sessionTags {
+		parts := strings.SplitN(tag, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid session tag %s, expected key=value", tag)
+		}
+		tags[parts[0]] = parts[1]
+	}
+	return tags, validateSessionTags(tags)
+}
+
+func validateSessionTags(tags map[string]string) error {
+	if len(tags) > MAX_SESSION_TAGS {
+		return fmt.Errorf("Too many session tags: %d, at most %d are allowed", len(tags), MAX_SESSION_TAGS)
+	}
+	for k, v := range tags {
+		if len(k) > MAX_SESSION_TAG_KEY_LENGTH {
+			return fmt.Errorf("Session tag key %s exceeds %d characters", k, MAX_SESSION_TAG_KEY_LENGTH)
+		}
+		if len(v) > MAX_SESSION_TAG_VALUE_LENGTH {
+			return fmt.Errorf("Session tag value of %s exceeds %d characters", k, MAX_SESSION_TAG_VALUE_LENGTH)
+		}
+	}
+	return nil
+}
+
+func toStsTags(tags map[string]string) []*sts.Tag {
+	var keys []string
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var ret []*sts.Tag
+	for _, k := range keys {
+		ret = append(ret, &sts.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	return ret
+}
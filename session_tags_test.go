@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionTags_MergeFileAndFlags(t *testing.T) {
+	tagsPath := path.Join(os.TempDir(), "swamp-tags-test.json")
+	defer os.Remove(tagsPath)
+	ioutil.WriteFile(tagsPath, []byte(`{"team": "team1", "cost-center": "42"}`), 0600)
+
+	c := NewSwampConfig()
+	c.sessionTagsFile = tagsPath
+	c.sessionTags = []string{"team=team2", "project=swamp"}
+
+	tags, err := c.GetSessionTags()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "team2", "cost-center": "42", "project": "swamp"}, tags)
+}
+
+func TestSessionTags_InvalidFlag(t *testing.T) {
+	c := NewSwampConfig()
+	c.sessionTags = []string{"team"}
+
+	_, err := c.GetSessionTags()
+	assert.Error(t, err)
+}
+
+func TestSessionTags_TooMany(t *testing.T) {
+	tags := map[string]string{}
+	for i := 0; i <= MAX_SESSION_TAGS; i++ {
+		tags[fmt.Sprintf("key%d", i)] = "value"
+	}
+
+	assert.Error(t, validateSessionTags(tags))
+}
+
+func TestSessionTags_ToStsTagsSorted(t *testing.T) {
+	tags := toStsTags(map[string]string{"b": "2", "a": "1"})
+
+	assert.Equal(t, "a", *tags[0].Key)
+	assert.Equal(t, "1", *tags[0].Value)
+	assert.Equal(t, "b", *tags[1].Key)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/go-ini/ini"
+)
+
+type profileStatus struct {
+	Profile    string     `json:"profile"`
+	Identity   string     `json:"identity"`
+	Expiration *time.Time `json:"expiration"`
+}
+
+// find all profiles written by swamp, identified by their expiration key
+func listManagedProfiles(credentialsPath string) ([]profileStatus, error) {
+	cfg, err := ini.Load(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading credentials file %s: %s", credentialsPath, err)
+	}
+
+	var profiles []profileStatus
+	for _, sec := range cfg.Sections() {
+		if !sec.HasKey(EXPIRATION_KEY) {
+			continue
+		}
+		ps := profileStatus{Profile: sec.Name()}
+		if expiration, err := time.Parse(time.RFC3339, sec.Key(EXPIRATION_KEY).String()); err == nil {
+			ps.Expiration = &expiration
+		}
+		profiles = append(profiles, ps)
+	}
+	return profiles, nil
+}
+
+func lookupIdentity(profile, region string) string {
+	sess, err := session.NewSessionWithOptions(newSessionOptions(&profile, &region))
+	if err != nil {
+		return "-"
+	}
+	output, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "-"
+	}
+	return *output.Arn
+}
+
+func formatExpiresIn(expiration *time.Time, now time.Time) string {
+	if expiration == nil {
+		return "unknown"
+	}
+	d := expiration.Sub(now)
+	if d <= 0 {
+		return "expired"
+	}
+	return d.Truncate(time.Second).String()
+}
+
+func writeStatus(w io.Writer, profiles []profileStatus, asJson, pretty bool, now time.Time) error {
+	if asJson {
+		if profiles == nil {
+			profiles = []profileStatus{}
+		}
+		return newJsonEncoder(w, pretty).Encode(profiles)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tIDENTITY\tEXPIRES IN")
+	for _, ps := range profiles {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", ps.Profile, ps.Identity, formatExpiresIn(ps.Expiration, now))
+	}
+	return tw.Flush()
+}
+
+func printStatus(w io.Writer, config *SwampConfig, pw *ProfileWriter) error {
+	profiles, err := listManagedProfiles(pw.credentialsPath)
+	if err != nil {
+		return err
+	}
+	for i := range profiles {
+		profiles[i].Identity = lookupIdentity(profiles[i].Profile, config.region)
+	}
+	return writeStatus(w, profiles, config.json, config.jsonPretty.Or(true), clock.Now())
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus_ListManagedProfiles(t *testing.T) {
+	credPath := path.Join(os.TempDir(), "swamp-status-test.ini")
+	defer os.Remove(credPath)
+	ioutil.WriteFile(credPath, []byte(`[default]
+aws_access_key_id = some-access-key
+
+[swamp]
+aws_access_key_id = some-access-key
+x_swamp_expiration = 2017-07-06T08:31:10Z
+`), 0600)
+
+	profiles, err := listManagedProfiles(credPath)
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 1)
+	assert.Equal(t, "swamp", profiles[0].Profile)
+	assert.Equal(t, time.Date(2017, 7, 6, 8, 31, 10, 0, time.UTC), *profiles[0].Expiration)
+}
+
+func TestStatus_WriteStatusTable(t *testing.T) {
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	expiration := now.Add(30 * time.Minute)
+	buf := new(bytes.Buffer)
+
+	err := writeStatus(buf, []profileStatus{{Profile: "swamp", Identity: "some-arn", Expiration: &expiration}}, false, true, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "PROFILE  IDENTITY  EXPIRES IN\nswamp    some-arn  30m0s\n", buf.String())
+}
+
+func TestStatus_WriteStatusJson(t *testing.T) {
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	buf := new(bytes.Buffer)
+
+	err := writeStatus(buf, []profileStatus{{Profile: "swamp", Identity: "some-arn", Expiration: &now}}, true, false, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"profile":"swamp","identity":"some-arn","expiration":"2017-07-06T08:00:00Z"}]`+"\n", buf.String())
+}
+
+func TestStatus_FormatExpiresIn(t *testing.T) {
+	now := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+
+	assert.Equal(t, "unknown", formatExpiresIn(nil, now))
+	assert.Equal(t, "expired", formatExpiresIn(&past, now))
+}
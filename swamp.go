@@ -2,23 +2,45 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
 )
 
+// command run before exiting on errors, see -on-error-hook
+var errorHook string
+
+const (
+	// exit code of -abort-on-skew, telling ci to fix the clock rather than retry
+	EXIT_CLOCK_SKEW = 3
+)
+
 func die(msg string, err error) {
 	dieSlow(msg, "", err)
 }
 
 func dieSlow(msg, longMsg string, err error) {
+	dieWithExitCode(1, msg, longMsg, err)
+}
+
+func dieWithExitCode(code int, msg, longMsg string, err error) {
 	fmt.Fprintln(os.Stderr, msg+":")
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, err)
@@ -26,12 +48,54 @@ func dieSlow(msg, longMsg string, err error) {
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, longMsg)
 	}
+	runErrorHook(errorHook, msg, err)
+	os.Exit(code)
+}
+
+// exit without the error details printed by die, e.g. after checks reported their results already
+func exitFailed(msg string, err error) {
+	runErrorHook(errorHook, msg, err)
 	os.Exit(1)
 }
 
-func getCallerId(svc *sts.STS) *sts.GetCallerIdentityOutput {
+func exitInvalidConfig(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	flag.Usage()
+	exitFailed("Invalid configuration", err)
+}
+
+// run hook with the failed step and error in its environment, failures of the hook are only reported
+func runErrorHook(hook, msg string, err error) {
+	if hook == "" {
+		return
+	}
+	c := exec.Command("/bin/sh", "-c", hook)
+	c.Env = append(os.Environ(), fmt.Sprintf("SWAMP_ERROR_STEP=%s", msg), fmt.Sprintf("SWAMP_ERROR=%s", err))
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running on error hook: %s\n", err)
+	}
+}
+
+// check whether err is caused by deactivated, deleted or otherwise invalid access keys
+func isInvalidClientTokenId(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == "InvalidClientTokenId"
+	}
+	return false
+}
+
+func invalidCredentialsHint(profile string) string {
+	return fmt.Sprintf(`Base profile %s credentials are invalid or expired; run "aws configure --profile %s"`, profile, profile)
+}
+
+func getCallerId(svc *sts.STS, profile string) *sts.GetCallerIdentityOutput {
 	output, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
 	if err != nil {
+		if isInvalidClientTokenId(err) {
+			dieSlow("Error fetching caller id", invalidCredentialsHint(profile), err)
+		}
 		die("Error fetching caller id", err)
 	}
 
@@ -42,9 +106,53 @@ func cleanTokenCode(tokenCode string) string {
 	return strings.Trim(tokenCode, " \r\n")
 }
 
-func fetchTokenCode(tokenSerialNumber string, cmd string) string {
+var tokenCodePattern = regexp.MustCompile(`(?:^|\D)(\d{6})(?:\D|$)`)
+
+// first 6-digit code in output of chatty otp helpers, output is kept if there is none
+func extractTokenCode(output string) string {
+	if m := tokenCodePattern.FindStringSubmatch(output); m != nil {
+		return m[1]
+	}
+	return output
+}
+
+const (
+	SHELL_NONE = "none"
+)
+
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	return "/bin/sh"
+}
+
+// argv running cmd with shell, a shell without arguments gets -c or /c for cmd,
+// cmd is split into fields without a shell
+func shellCommand(shell, cmd string) []string {
+	if shell == SHELL_NONE {
+		return strings.Fields(cmd)
+	}
+	argv := strings.Fields(shell)
+	if len(argv) == 1 {
+		if name := strings.ToLower(filepath.Base(argv[0])); name == "cmd" || name == "cmd.exe" {
+			argv = append(argv, "/c")
+		} else {
+			argv = append(argv, "-c")
+		}
+	}
+	return append(argv, cmd)
+}
+
+// env is added to the inherited environment, overriding variables already set
+func fetchTokenCode(tokenSerialNumber string, cmd string, shell string, env []string) string {
 	printer.Printf("Obtaining mfa token for: %s\n", tokenSerialNumber)
-	if output, err := exec.Command("/bin/sh", "-c", cmd).Output(); err != nil {
+	argv := shellCommand(shell, cmd)
+	command := exec.Command(argv[0], argv[1:]...)
+	if len(env) > 0 {
+		command.Env = append(os.Environ(), env...)
+	}
+	if output, err := command.Output(); err != nil {
 		die("Error obtaining mfa token", err)
 		return ""
 	} else {
@@ -52,9 +160,28 @@ func fetchTokenCode(tokenSerialNumber string, cmd string) string {
 	}
 }
 
-func askForTokenCode(tokenSerialNumber string) string {
+// output modes printing credentials or their expiry to stdout for eval or other programs
+func credentialsOnStdout(config *SwampConfig) bool {
+	return config.credentialProcess || config.printEnvExport || config.printExpiry != ""
+}
+
+// messages and mfa prompts go to stderr when stdout carries output for scripts or a program drives swamp via -json-fd
+func messageOutput(config *SwampConfig) io.Writer {
+	if credentialsOnStdout(config) || config.jsonFd > 0 {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func formatPrompt(config *SwampConfig, serialNumber string) string {
+	return strings.Replace(config.promptTemplate, SERIAL_PLACEHOLDER, serialNumber, -1)
+}
+
+func askForTokenCode(config *SwampConfig, serialNumber string) string {
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("Enter mfa token for %s: ", tokenSerialNumber)
+	if !config.noPrompt {
+		fmt.Fprint(messageOutput(config), formatPrompt(config, serialNumber))
+	}
 	if tokenCode, err := reader.ReadString('\n'); err != nil {
 		die("Error reading mfa token", err)
 		return ""
@@ -63,14 +190,60 @@ func askForTokenCode(tokenSerialNumber string) string {
 	}
 }
 
-func getTokenCode(config *SwampConfig) string {
-	var tokenCode string
-	if config.mfaExec != "" {
-		tokenCode = fetchTokenCode(config.tokenSerialNumber, config.mfaExec)
+func computeTokenCode(config *SwampConfig, serialNumber string) string {
+	var t *totp
+	var err error
+	if config.totpSecretFile != "" {
+		t, err = readTotpFile(config.totpSecretFile)
 	} else {
-		tokenCode = askForTokenCode(config.tokenSerialNumber)
+		t, err = parseTotp(config.totpSecret)
+	}
+	if err != nil {
+		die("Error computing mfa token", err)
+	}
+	printer.Printf("Computing mfa token for: %s\n", serialNumber)
+	tokenCode := t.Code(clock.Now())
+	if config.tokenCodes.Used(serialNumber, tokenCode) {
+		printer.Printf("Waiting for the next mfa token for: %s, sts rejects reusing one\n", serialNumber)
+		clock.Sleep(t.UntilNextPeriod(clock.Now()))
+		tokenCode = t.Code(clock.Now())
+	}
+	return tokenCode
+}
+
+func getTokenCode(config *SwampConfig, serialNumber string) string {
+	tokenCode, err := newTokenPrompt(config).TokenCode(serialNumber)
+	if err != nil {
+		die("Error obtaining mfa token", err)
 	}
-	return cleanTokenCode(tokenCode)
+	tokenCode = cleanTokenCode(tokenCode)
+	if config.tokenCodes.Used(serialNumber, tokenCode) {
+		printer.Printf("Warning: mfa token for %s was used already, sts will reject it\n", serialNumber)
+	}
+	config.tokenCodes.Put(serialNumber, tokenCode)
+	return tokenCode
+}
+
+type baseCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// run cmd and parse its output as base credentials, e.g. decrypted with sops
+func fetchBaseCredentials(cmd string) (*credentials.Credentials, error) {
+	output, err := exec.Command("/bin/sh", "-c", cmd).Output()
+	if err != nil {
+		return nil, err
+	}
+	var c baseCredentials
+	if err := json.Unmarshal(output, &c); err != nil {
+		return nil, fmt.Errorf("Error parsing base credentials: %s", err)
+	}
+	if c.AccessKeyId == "" || c.SecretAccessKey == "" {
+		return nil, errors.New("Base credentials must contain AccessKeyId and SecretAccessKey")
+	}
+	return credentials.NewStaticCredentials(c.AccessKeyId, c.SecretAccessKey, c.SessionToken), nil
 }
 
 func validateSessionToken(options session.Options) bool {
@@ -95,13 +268,17 @@ func guessCurrentProfile(config *SwampConfig) string {
 
 func getSessionToken(sess *session.Session, config *SwampConfig) *sts.Credentials {
 	svc := sts.New(sess)
-	tokenCode := getTokenCode(config)
+	abortOnSkew(config, sess)
+	tokenCode := getTokenCode(config, config.tokenSerialNumber)
 	output, err := svc.GetSessionToken(&sts.GetSessionTokenInput{
 		DurationSeconds: &config.intermediateDuration,
 		SerialNumber:    &config.tokenSerialNumber,
 		TokenCode:       &tokenCode,
 	})
 	if err != nil {
+		if isInvalidClientTokenId(err) {
+			dieSlow("Error getting session token", invalidCredentialsHint(guessCurrentProfile(config)), err)
+		}
 		dieSlow("Error getting session token", fmt.Sprintf(`Make sure your current profile %s is valid and allows running "aws sts get-session-token".`, guessCurrentProfile(config)), err)
 	}
 
@@ -113,55 +290,334 @@ func getIntermediateSessionOptions(config *SwampConfig) session.Options {
 }
 
 func getBaseSessionOptions(config *SwampConfig) session.Options {
-	return newSessionOptions(&config.profile, &config.region)
+	options := newSessionOptions(&config.profile, &config.region)
+	if config.baseExec != "" {
+		cred, err := fetchBaseCredentials(config.baseExec)
+		if err != nil {
+			die("Error obtaining base credentials", err)
+		}
+		options.Config.Credentials = cred
+	}
+	return options
 }
 
+// shared config is always loaded to support profiles with credential_process or source_profile
 func newSessionOptions(profile, region *string) session.Options {
 	return session.Options{
-		Config:  aws.Config{Region: region},
-		Profile: *profile}
+		Config:            aws.Config{Region: region, HTTPClient: sessionHTTPClient},
+		Handlers:          sessionHandlers(),
+		Profile:           *profile,
+		SharedConfigState: session.SharedConfigEnable}
+}
+
+// exit with EXIT_CLOCK_SKEW instead of sending a token code sts would reject for the clock being off
+func abortOnSkew(config *SwampConfig, sess *session.Session) {
+	if config.abortOnSkew == 0 {
+		return
+	}
+	err := checkSessionClockSkew(sess, config.abortOnSkew)
+	if _, ok := err.(*clockSkewError); ok {
+		dieWithExitCode(EXIT_CLOCK_SKEW, "Error checking clock skew", CLOCK_SKEW_HINT, err)
+	} else if err != nil {
+		die("Error checking clock skew", err)
+	}
 }
 
 // validate session token and request a new one if it's invalid.
 // write target profile into .aws/credentials
-func ensureSessionTokenProfile(config *SwampConfig, pw *ProfileWriter) {
-	printer.Printf("Checking if profile %s is still valid\n", config.intermediateProfile)
-	if validateSessionToken(getIntermediateSessionOptions(config)) {
+// returns whether a new session token was written
+func ensureSessionTokenProfile(config *SwampConfig, pw *ProfileWriter) bool {
+	if !config.intermediateReuse {
+		printer.Printf("Requesting new session token for profile %s\n", config.intermediateProfile)
+	} else {
+		printer.Printf("Checking if profile %s is still valid\n", config.intermediateProfile)
+	}
+	if config.intermediateReuse && validateSessionToken(getIntermediateSessionOptions(config)) {
 		printer.Printf("Session token for profile %s is still valid\n", config.intermediateProfile)
+		if config.targetRole == "" {
+			health.SetExpiration(pw.ReadExpiration(config.intermediateProfile))
+		}
+		return false
 	} else {
 		sess := session.Must(session.NewSessionWithOptions(getBaseSessionOptions(config)))
 		cred := getSessionToken(sess, config)
 		if err := pw.WriteProfile(cred, &config.intermediateProfile, sess.Config.Region); err != nil {
 			die("Error writing profile", err)
 		}
+		if config.targetRole == "" {
+			health.SetExpiration(cred.Expiration)
+		}
+		return true
 	}
 }
 
-func assumeRole(svc *sts.STS, roleArn, roleSessionName *string, duration *int64) *sts.Credentials {
-	output, err := svc.AssumeRole(&sts.AssumeRoleInput{
-		RoleArn:         roleArn,
-		RoleSessionName: roleSessionName,
-		DurationSeconds: duration,
+func assumeRole(svc *sts.STS, input *sts.AssumeRoleInput) *sts.Credentials {
+	return assumeRoleWaitingForPropagation(svc, input, 0)
+}
+
+// assume-role retrying for up to propagationTimeout while a new role is not assumable yet
+func assumeRoleWaitingForPropagation(svc *sts.STS, input *sts.AssumeRoleInput, propagationTimeout time.Duration) *sts.Credentials {
+	var output *sts.AssumeRoleOutput
+	err := retryOnPropagation(assumeRolePropagationTimeout(input, propagationTimeout), func() error {
+		var err error
+		output, err = svc.AssumeRole(input)
+		return err
 	})
 	if err != nil {
-		dieSlow("Error assuming role", fmt.Sprintf(`Make sure your current profile is valid and allows running "aws sts assume-role --role-arn %s"`, *roleArn), err)
+		if isPackedPolicyTooLarge(err) {
+			dieSlow("Error assuming role", "The session policies exceed the size limit of sts, use fewer or smaller policies.", err)
+		}
+		dieSlow("Error assuming role", fmt.Sprintf(`Make sure your current profile is valid and allows running "aws sts assume-role --role-arn %s"`, *input.RoleArn), err)
 	}
+	warnOnPackedPolicySize(output.PackedPolicySize)
 
 	return output.Credentials
 }
 
-// assume-role into target account and write target profile into .aws/credentials
-func ensureTargetProfile(config *SwampConfig, pw *ProfileWriter, sess *session.Session) {
+// the base identity must be in the expected account if one is given
+func checkBaseAccount(expected string, callerId *sts.GetCallerIdentityOutput) error {
+	if expected == "" || *callerId.Account == expected {
+		return nil
+	}
+	return fmt.Errorf("Base identity %s is in account %s instead of %s", *callerId.Arn, *callerId.Account, expected)
+}
+
+// with -base-account-id fail before anything is requested with the base profile, like a session token with mfa prompt
+func verifyBaseAccount(config *SwampConfig) {
+	if config.baseAccountId == "" {
+		return
+	}
+	profile := guessCurrentProfile(config)
+	sess := session.Must(session.NewSessionWithOptions(getBaseSessionOptions(config)))
+	if err := checkBaseAccount(config.baseAccountId, getCallerId(sts.New(sess), profile)); err != nil {
+		dieSlow("Error checking base identity", fmt.Sprintf("Check profile %s and AWS_* environment variables overriding it", profile), err)
+	}
+}
+
+// with -account-from-caller the target role is in the account of the base identity
+func resolveCallerAccount(config *SwampConfig, callerId *sts.GetCallerIdentityOutput) error {
+	if !config.accountFromCaller {
+		return nil
+	}
+	config.targetAccount = *callerId.Account
+	if arn := *config.GetRoleArn(); !assumableRoleArnPattern.MatchString(arn) {
+		return fmt.Errorf("Invalid target role arn %s", arn)
+	}
+	return nil
+}
+
+// assume-role into target account
+func assumeTargetRole(config *SwampConfig, sess *session.Session, baseProfile string) *sts.Credentials {
 	svc := sts.New(sess)
 
-	userId := getCallerId(svc).Arn
+	callerId := getCallerId(svc, guessCurrentProfile(config))
+	if err := resolveCallerAccount(config, callerId); err != nil {
+		die("Error resolving target role", err)
+	}
+	userId := callerId.Arn
 	parts := strings.Split(*userId, "/")
-	roleSessionName := parts[len(parts)-1]
+	roleSessionName := buildSessionName(parts[len(parts)-1], config.reason, config.sessionNameMaxLen, config.sessionNameHash)
+
+	// assuming a role with credentials of an assumed role is role chaining
+	chaining := isAssumedRole(*userId)
+	// account of the credentials assuming the target role
+	sessionAccount := *callerId.Account
+	var lastHop *chainHop
+	if len(config.roleChain) > 0 {
+		lastHop = &config.roleChain[len(config.roleChain)-1]
+		sess = assumeChainHops(sess, config.roleChain[:len(config.roleChain)-1], roleSessionName, chaining)
+		svc = sts.New(sess)
+		chaining = chaining || len(config.roleChain) > 1
+		if len(config.roleChain) > 1 {
+			sessionAccount = arnAccount(config.roleChain[len(config.roleChain)-2].RoleArn)
+		}
+	}
+
+	duration := config.targetDuration
+	if config.targetDurationMax {
+		if config.autoClampDuration {
+			printer.Printf("Ignoring -auto-clamp-duration, -target-duration=max requests the max session duration of %s already\n", *config.GetRoleArn())
+		}
+		duration = maxTargetDuration(sess, sessionAccount, *config.GetRoleArn(), duration)
+	} else if config.autoClampDuration {
+		duration = clampTargetDuration(sess, sessionAccount, *config.GetRoleArn(), duration)
+	}
+	if chaining {
+		duration = clampChainedDuration(*config.GetRoleArn(), duration)
+	}
+	if config.targetDurationMax || duration < config.targetDuration {
+		// renew according to the duration actually requested
+		config.targetDuration = duration
+	}
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         config.GetRoleArn(),
+		RoleSessionName: &roleSessionName,
+		DurationSeconds: &duration,
+	}
+	if len(config.policyArns) > 0 {
+		input.PolicyArns = toStsPolicyArns(config.policyArns)
+	}
+	if config.policy != "" {
+		input.Policy = &config.policy
+	}
+	if tags, err := config.GetSessionTags(); err != nil {
+		die("Error reading session tags", err)
+	} else if len(tags) > 0 {
+		input.Tags = toStsTags(tags)
+	}
+	if config.externalId != "" {
+		input.ExternalId = &config.externalId
+	}
+	if lastHop != nil {
+		lastHop.apply(input)
+	}
+	if config.sourceIdentityFromSso {
+		if sourceIdentity, ok := ssoSourceIdentity(*userId); ok {
+			input.SourceIdentity = &sourceIdentity
+		} else {
+			printer.Printf("Caller %s is no sso user, not setting source identity\n", *userId)
+		}
+	}
+	var cliCachePath string
+	if config.targetMfaDevice != "" {
+		input.SerialNumber = &config.targetMfaDevice
+		if config.mfaCacheScope == MFA_CACHE_SCOPE_AWS_CLI && !awsCliCacheable(input) {
+			printer.Printf("Not using aws cli cache for %s, it has no entries for sessions with policies, tags or source identity\n", *input.RoleArn)
+		} else if config.mfaCacheScope == MFA_CACHE_SCOPE_AWS_CLI {
+			var err error
+			if cliCachePath, err = getAwsCliCachePath(awsCliCacheKey(input, lastHop != nil && lastHop.SessionName != "")); err != nil {
+				die("Error finding aws cli cache", err)
+			}
+			if cred := readAwsCliCache(cliCachePath, clock.Now()); cred != nil {
+				printer.Printf("Using credentials for %s from aws cli cache\n", *input.RoleArn)
+				return cred
+			}
+		}
+		tokenCode := config.targetTokenCode
+		if tokenCode == "" {
+			abortOnSkew(config, sess)
+			tokenCode = getTokenCode(config, config.targetMfaDevice)
+		}
+		input.TokenCode = &tokenCode
+	}
+
+	if region := aws.StringValue(sess.Config.Region); region != "" {
+		if err := checkRolePartition(*input.RoleArn, region); err != nil {
+			die("Error assuming role", err)
+		}
+	}
+
+	if config.printAssumeCommand {
+		maskExternalId := config.externalIdFromEnv
+		if lastHop != nil && lastHop.ExternalId != "" {
+			maskExternalId = lastHop.externalIdFromEnv
+		}
+		printer.Println(assumeRoleCommand(input, maskExternalId, baseProfile, aws.StringValue(sess.Config.Region)))
+	}
 
-	cred := assumeRole(svc, config.GetRoleArn(), &roleSessionName, &config.targetDuration)
-	if err := pw.WriteProfile(cred, &config.targetProfile, sess.Config.Region); err != nil {
+	cred := assumeRoleWaitingForPropagation(svc, input, config.propagationTimeout)
+	if cliCachePath != "" {
+		if err := writeAwsCliCache(cliCachePath, cred); err != nil {
+			printer.Printf("Unable to write aws cli cache %s: %s\n", cliCachePath, err)
+		}
+	}
+	return cred
+}
+
+// file the target profiles are written to
+func targetCredentialsPath(config *SwampConfig, pw *ProfileWriter) string {
+	if config.credentialsFileFormat == CREDENTIALS_FORMAT_JSON {
+		return pw.jsonCredentialsPath()
+	}
+	return pw.credentialsPath
+}
+
+// target profiles go to the json credentials file with -credentials-file-format=json,
+// the intermediate profile always stays in the ini file read by the aws sdk
+func writeTargetProfile(config *SwampConfig, pw *ProfileWriter, cred *sts.Credentials, profileName, region *string) error {
+	if config.credentialsFileFormat == CREDENTIALS_FORMAT_JSON {
+		return pw.WriteJsonProfile(cred, profileName)
+	}
+	return pw.WriteProfile(cred, profileName, region)
+}
+
+// assume-role into target account, or get a federation token, and write target profile into .aws/credentials
+// or hand the credentials to the credential server if given
+func ensureTargetProfile(config *SwampConfig, pw *ProfileWriter, cs *credentialServer, sess *session.Session, baseProfile string) *sts.Credentials {
+	var cred *sts.Credentials
+	if config.federationName != "" {
+		cred = getFederationToken(config, sess, baseProfile)
+	} else {
+		cred = assumeTargetRole(config, sess, baseProfile)
+	}
+	if config.profilePerAccount {
+		config.targetProfile = accountProfileName(getAssumedAccount(sess, cred))
+	} else if config.profileTemplate != "" {
+		config.targetProfile = config.profileFromTemplate(getAssumedAccount(sess, cred))
+	}
+	region := sess.Config.Region
+	if config.assumeRoleRegion != "" {
+		region = &config.region
+	}
+	region = config.profileRegion(config.targetProfile, region)
+	if cs != nil {
+		cs.SetCredentials(cred)
+		printer.Printf("Token is valid until: %v\n", cred.Expiration)
+	} else if err := writeTargetProfile(config, pw, cred, &config.targetProfile, region); err != nil {
 		die("Error writing profile", err)
+	} else {
+		for _, region := range config.GetRegionSet() {
+			profile := regionalProfileName(config.targetProfile, region)
+			if err := writeTargetProfile(config, pw, cred, &profile, &region); err != nil {
+				die("Error writing profile", err)
+			}
+		}
+		for _, alias := range config.profileAliases {
+			if err := writeTargetProfile(config, pw, cred, &alias, config.profileRegion(alias, region)); err != nil {
+				die("Error writing profile", err)
+			}
+		}
+	}
+	if err := writeOutputs(config, cred); err != nil {
+		die("Error writing credentials", err)
+	}
+	if config.printCallerIdentityAfter {
+		id := getAssumedIdentity(sess, cred)
+		printer.Printf("Assumed identity %s in account %s\n", *id.Arn, *id.Account)
+	}
+	health.SetExpiration(cred.Expiration)
+	return cred
+}
+
+// sso user name from an assumed AWSReservedSSO_* role arn
+func ssoSourceIdentity(callerArn string) (string, bool) {
+	parts := strings.Split(callerArn, "/")
+	if len(parts) != 3 || !strings.HasSuffix(parts[0], ":assumed-role") || !strings.HasPrefix(parts[1], "AWSReservedSSO_") {
+		return "", false
 	}
+	return parts[2], true
+}
+
+func accountProfileName(account string) string {
+	return "acct-" + account
+}
+
+// caller identity of the given credentials
+func getAssumedIdentity(sess *session.Session, cred *sts.Credentials) *sts.GetCallerIdentityOutput {
+	svc := sts.New(sess, &aws.Config{
+		Credentials: credentials.NewStaticCredentials(*cred.AccessKeyId, *cred.SecretAccessKey, *cred.SessionToken),
+	})
+	output, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		die("Error fetching caller id of assumed role", err)
+	}
+	return output
+}
+
+// account id of the caller identity of the given credentials
+func getAssumedAccount(sess *session.Session, cred *sts.Credentials) string {
+	return *getAssumedIdentity(sess, cred).Account
 }
 
 func cleanCredentialsFromEnv(env []string) []string {
@@ -179,9 +635,30 @@ func cleanCredentialsFromEnv(env []string) []string {
 	return ret
 }
 
-func execCommand(config *SwampConfig) error {
+// environment of the executed command according to -exec-env
+func execEnvironment(config *SwampConfig, cred *sts.Credentials) []string {
+	env := os.Environ()
+	if !config.execKeepEnv {
+		env = cleanCredentialsFromEnv(env)
+	}
+	if config.execEnv != EXEC_ENV_CREDENTIALS {
+		env = append(env, fmt.Sprintf("AWS_PROFILE=%s", config.targetProfile))
+	}
+	if config.execEnv != EXEC_ENV_PROFILE && cred != nil {
+		env = append(env,
+			fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", *cred.AccessKeyId),
+			fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", *cred.SecretAccessKey),
+			fmt.Sprintf("AWS_SESSION_TOKEN=%s", *cred.SessionToken))
+		if config.region != "" {
+			env = append(env, fmt.Sprintf("AWS_REGION=%s", config.region))
+		}
+	}
+	return env
+}
+
+func execCommand(config *SwampConfig, cred *sts.Credentials) error {
 	c := exec.Command("/bin/sh", "-c", config.exec)
-	c.Env = append(cleanCredentialsFromEnv(os.Environ()), fmt.Sprintf("AWS_PROFILE=%s", config.targetProfile))
+	c.Env = execEnvironment(config, cred)
 	c.Stdin = os.Stdin
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
@@ -193,48 +670,281 @@ func main() {
 	config := NewSwampConfig()
 	config.SetupFlags()
 	flag.Parse()
+	config.DeriveIntermediateProfile(flag.CommandLine)
 
 	// setup logging
 	if config.quiet {
 		printer.SetOff(true)
 	}
+	printer.SetOutput(messageOutput(config))
+
+	if config.combinedFile != "" {
+		// read and write profiles of the combined file only, also for executed commands
+		os.Setenv("AWS_CONFIG_FILE", config.combinedFile)
+		os.Setenv("AWS_SHARED_CREDENTIALS_FILE", config.combinedFile)
+	}
+	errorHook = config.onErrorHook
 
 	// check user input on command line flags
+	if config.configCheck {
+		if err := config.Validate(); err != nil {
+			exitInvalidConfig(err)
+		}
+		if err := setupHTTPClient(config); err != nil {
+			die("Error setting up http client", err)
+		}
+		// the config check loads config files on its own to report errors instead of dying
+		if !configCheck(os.Stdout, config) {
+			exitFailed("Checking config", errors.New("Config check failed"))
+		}
+		return
+	}
+	var watcher *configWatcher
+	if config.watchConfig {
+		watcher = newConfigWatcher(*config)
+	}
+	if err := config.LoadConfigFile(); err != nil {
+		die("Error reading config file", err)
+	}
+	if err := config.LoadRoleChain(); err != nil {
+		die("Error reading assume role chain file", err)
+	}
+	if err := config.validatePolicyFromStdin(); err != nil {
+		exitInvalidConfig(err)
+	}
+	if err := config.LoadSessionPolicy(); err != nil {
+		die("Error reading session policy", err)
+	}
 	if err := config.Validate(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		flag.Usage()
-		os.Exit(1)
+		exitInvalidConfig(err)
 	}
-	if config.aliasConfig == "" {
-		assume(config)
-	} else {
+	if err := setupHTTPClient(config); err != nil {
+		die("Error setting up http client", err)
+	}
+	if watcher != nil {
+		watcher.Loaded(config)
+	}
+	switch {
+	case config.status:
+		pw, err := NewProfileWriter()
+		if err != nil {
+			die("Error initializing profile writer", err)
+		}
+		if err := printStatus(os.Stdout, config, pw); err != nil {
+			die("Error listing profiles", err)
+		}
+	case config.configDump:
+		if err := dumpConfig(os.Stdout, flag.CommandLine, config, config.json, config.jsonPretty.Or(true)); err != nil {
+			die("Error printing config", err)
+		}
+	case config.listRegions:
+		partition := config.partition
+		if partition == "" {
+			partition = config.GetPartition()
+		}
+		if err := listRegions(os.Stdout, partition); err != nil {
+			die("Error listing regions", err)
+		}
+	case config.printProfilePath:
+		if err := printProfilePaths(os.Stdout, config.credentialsFileFormat); err != nil {
+			die("Error resolving profile paths", err)
+		}
+	case config.doctor:
+		if !doctor(os.Stdout, config) {
+			exitFailed("Running doctor", errors.New("Doctor found problems"))
+		}
+	case config.mfaDevices:
+		if err := listMfaDevices(os.Stdout, config); err != nil {
+			die("Error listing mfa devices", err)
+		}
+	case config.debugTrustPolicy:
+		if err := debugTrustPolicy(os.Stdout, config); err != nil {
+			die("Error debugging trust policy", err)
+		}
+	case config.configureBase:
+		if err := configureBase(config, initProfileWriter(config)); err != nil {
+			die("Error configuring base profile", err)
+		}
+	case config.aliasConfig != "" && config.aliasCheck != "":
+		if ok, err := checkAliases(os.Stdout, config.aliasConfig, config.aliasCheck); err != nil {
+			die("Error checking aliases", err)
+		} else if !ok {
+			exitFailed("Checking aliases", errors.New("Alias check failed"))
+		}
+	case config.aliasConfig != "":
 		if err := generateAliases(os.Stdout, config.aliasConfig); err != nil {
 			die("Error generating alias config", err)
 		}
+	case config.credentialProcess:
+		runCredentialProcess(os.Stdout, config)
+	default:
+		assume(config, watcher)
+	}
+}
+
+// session options used as base for assume-role into target account,
+// -assume-role-region overrides the region of sts
+func getAssumeRoleSessionOptions(config *SwampConfig) session.Options {
+	options := getAssumeRoleBaseSessionOptions(config)
+	if config.assumeRoleRegion != "" {
+		options.Config.Region = &config.assumeRoleRegion
 	}
+	return options
 }
 
-func assume(config *SwampConfig) {
-	baseProfile := &config.profile
+func getAssumeRoleBaseSessionOptions(config *SwampConfig) session.Options {
+	if config.chainFromProfile != "" {
+		options := newSessionOptions(&config.chainFromProfile, &config.region)
+		if validateSessionToken(options) {
+			printer.Printf("Chaining from profile %s\n", config.chainFromProfile)
+			return options
+		}
+		printer.Printf("Profile %s is not valid anymore, falling back to base profile\n", config.chainFromProfile)
+	}
 	if config.tokenSerialNumber != "" {
-		baseProfile = &config.intermediateProfile
+		return getIntermediateSessionOptions(config)
 	}
+	return getBaseSessionOptions(config)
+}
+
+// wait this long before renewing credentials
+func renewInterval(config *SwampConfig) time.Duration {
+	return time.Second * time.Duration(config.targetDuration/2)
+}
+
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// renew up to -refresh-jitter earlier to spread renewals of many hosts
+func renewSleep(config *SwampConfig) time.Duration {
+	interval := renewInterval(config)
+	if config.refreshJitter > 0 {
+		interval -= time.Duration(jitterRand.Int63n(int64(config.refreshJitter)))
+	}
+	return interval
+}
+
+// whether the renew loop ran -renew-max-iterations times or the next run would be later than -renew-for after started
+func renewLimitReached(config *SwampConfig, runs int, started, next time.Time) bool {
+	if config.renewMaxIterations > 0 && runs >= config.renewMaxIterations {
+		return true
+	}
+	return config.renewFor > 0 && next.Sub(started) > config.renewFor
+}
+
+// log when credentials are nearing expiry and when they expired without refreshing them
+func warnOnExpiry(config *SwampConfig) {
+	expiration := health.Expiration()
+	if expiration == nil {
+		return
+	}
+	clock.Sleep(renewInterval(config))
+	printer.Printf("Warning: credentials expire at %v\n", *expiration)
+	if remaining := expiration.Sub(clock.Now()); remaining > 0 {
+		clock.Sleep(remaining)
+	}
+	printer.Printf("Warning: credentials expired at %v\n", *expiration)
+}
+
+// profile writer with the write options of config
+func initProfileWriter(config *SwampConfig) *ProfileWriter {
 	pw, err := NewProfileWriter()
 	if err != nil {
 		die("Error initializing profile writer", err)
 	}
+	configureProfileWriter(pw, config)
+	return pw
+}
+
+// apply the settings of config to pw, again after each reload of the config files
+func configureProfileWriter(pw *ProfileWriter, config *SwampConfig) {
+	pw.validateWrite = config.validateWrite
+	pw.sortProfiles = config.sortProfiles
+	pw.protected = config.protectedProfiles
+	pw.force = config.force
+	pw.backupKeep = 0
+	if config.credentialsBackup {
+		pw.backupKeep = config.backupKeep
+	}
+	pw.combined = config.combinedFile != ""
+	pw.managedBlock = config.managedBlock
+}
+
+// watcher reloads config files before each renewal if given
+func assume(config *SwampConfig, watcher *configWatcher) {
+	lastRunPath, ok := checkMinInterval(config)
+	if !ok {
+		return
+	}
+	resolveRegion(config, detectEc2Region)
+	verifyBaseAccount(config)
+	pw := initProfileWriter(config)
+	if config.healthAddr != "" {
+		serveHealth(config.healthAddr, health)
+	}
+	var cs *credentialServer
+	if config.credentialServerAddr != "" {
+		var err error
+		if cs, err = newCredentialServer(); err != nil {
+			die("Error initializing credential server", err)
+		}
+		cs.Serve(config.credentialServerAddr)
+	}
+	var warner *expiryWarner
+	if config.warnBeforeExpiry > 0 && isInteractive() {
+		warner = newExpiryWarner(config.warnBeforeExpiry, runtime.GOOS)
+	}
+	started := clock.Now()
+	runs := 0
+	if config.renewInBackground && os.Getenv(BACKGROUND_ENV) != "" {
+		// the foreground process did the first run already
+		runs++
+		sleep := renewSleep(config)
+		if renewLimitReached(config, runs, started, clock.Now().Add(sleep)) {
+			return
+		}
+		clock.Sleep(sleep)
+	}
 	for {
+		runs++
+		if watcher != nil && runs > 1 {
+			if changes, err := watcher.Reload(config); err != nil {
+				printer.Printf("Keeping previous config, error reloading config: %s\n", err)
+			} else {
+				configureProfileWriter(pw, config)
+				for _, change := range changes {
+					printer.Printf("Reloaded config, %s\n", change)
+				}
+			}
+		}
+		// hold back output until we know whether anything changed
+		var output *bytes.Buffer
+		changed := false
+		if config.quietIfValid {
+			output = new(bytes.Buffer)
+			printer.SetOutput(output)
+		}
+
 		if config.tokenSerialNumber != "" {
 			// get intermediate session token with mfa, use that to assume role into target account
-			ensureSessionTokenProfile(config, pw)
+			changed = ensureSessionTokenProfile(config, pw)
 		}
 
-		if config.targetRole != "" {
-			sess := session.Must(session.NewSessionWithOptions(newSessionOptions(baseProfile, &config.region)))
-			ensureTargetProfile(config, pw, sess)
+		if config.targetRole != "" || config.federationName != "" {
+			var cred *sts.Credentials
+			if config.quietIfValid && validateSessionToken(newSessionOptions(&config.targetProfile, &config.region)) {
+				printer.Printf("Target profile %s is still valid\n", config.targetProfile)
+			} else {
+				options := getAssumeRoleSessionOptions(config)
+				sess := session.Must(session.NewSessionWithOptions(options))
+				cred = ensureTargetProfile(config, pw, cs, sess, options.Profile)
+				changed = true
+				if warner != nil {
+					warner.Schedule(config.targetProfile, cred.Expiration)
+				}
+			}
 
 			if config.exec != "" {
-				if err := execCommand(config); err != nil {
+				if err := execCommand(config, cred); err != nil {
 					die(fmt.Sprintf(`Error running command ""%s" with AWS profile "%s"`, config.exec, config.targetProfile), err)
 				} else {
 					printer.Printf("Executed \"%s\" sucessfully\n", config.exec)
@@ -242,9 +952,41 @@ func assume(config *SwampConfig) {
 			}
 		}
 
-		if !config.renew {
-			break
+		if runs == 1 {
+			markMinInterval(lastRunPath)
+		}
+
+		if output != nil {
+			printer.SetOutput(messageOutput(config))
+			if changed {
+				messageOutput(config).Write(output.Bytes())
+			}
+		}
+
+		switch config.onExpiry {
+		case ON_EXPIRY_RENEW:
+			if config.renewInBackground && os.Getenv(BACKGROUND_ENV) == "" {
+				pid, err := startBackground(config.backgroundLog)
+				if err != nil {
+					die("Error starting background renewal", err)
+				}
+				printer.Printf("Renewing in background process %d, logging to %s\n", pid, config.backgroundLog)
+				return
+			}
+			sleep := renewSleep(config)
+			if renewLimitReached(config, runs, started, clock.Now().Add(sleep)) {
+				printer.Printf("Stopping renewal after %d runs\n", runs)
+				return
+			}
+			clock.Sleep(sleep)
+			if config.renewIfUsed {
+				waitForConsumer(targetCredentialsPath(config, pw), cs)
+			}
+		case ON_EXPIRY_WARN:
+			warnOnExpiry(config)
+			return
+		default:
+			return
 		}
-		time.Sleep(time.Second * time.Duration(config.targetDuration/2))
 	}
 }
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestSwamp_ExecutingMFACommand(t *testing.T) {
-	tokenCode := fetchTokenCode("some-device-id", "echo 1234")
+	tokenCode := fetchTokenCode("some-device-id", "echo 1234", "/bin/sh", nil)
 
 	assert.EqualValues(t, "1234\n", tokenCode)
 }
@@ -18,7 +30,7 @@ func TestSwamp_GetTokenCodeWithMFACommandTrimNewLine(t *testing.T) {
 	config.tokenSerialNumber = "some-device-id"
 	config.mfaExec = "echo 123456\n"
 
-	tokenCode := getTokenCode(config)
+	tokenCode := getTokenCode(config, config.tokenSerialNumber)
 
 	assert.EqualValues(t, "123456", tokenCode)
 }
@@ -27,7 +39,7 @@ func TestSwamp_ExecCommand_ExitCode_Zero(t *testing.T) {
 	config := NewSwampConfig()
 	config.exec = "true"
 
-	err := execCommand(config)
+	err := execCommand(config, nil)
 
 	assert.NoError(t, err)
 }
@@ -36,7 +48,7 @@ func TestSwamp_ExecCommand_ExitCode_NonZero(t *testing.T) {
 	config := NewSwampConfig()
 	config.exec = "false"
 
-	err := execCommand(config)
+	err := execCommand(config, nil)
 
 	assert.Error(t, err)
 }
@@ -50,7 +62,409 @@ func TestSwamp_ExecCommand_ExitCode_EnvironmentHasNotAwsCredentials(t *testing.T
 	config.targetProfile = "some-target-profile"
 	config.exec = `test -z "${AWS_ACCESS_KEY_ID}" && test -z "${AWS_SECRET_ACCESS_KEY}" && test -z "${AWS_SESSION_TOKEN}"`
 
-	err := execCommand(config)
+	err := execCommand(config, nil)
 
 	assert.NoError(t, err)
 }
+
+func TestSwamp_ExecCommand_ExitCode_EnvironmentKeepsAwsCredentials(t *testing.T) {
+	os.Setenv("AWS_ACCESS_KEY_ID", "some-access-key-id")
+	defer os.Clearenv()
+
+	config := NewSwampConfig()
+	config.targetProfile = "some-target-profile"
+	config.execKeepEnv = true
+	config.exec = `test "${AWS_ACCESS_KEY_ID}" = some-access-key-id && test "${AWS_PROFILE}" = some-target-profile`
+
+	err := execCommand(config, nil)
+
+	assert.NoError(t, err)
+}
+
+func TestSwamp_IsInvalidClientTokenId(t *testing.T) {
+	assert.True(t, isInvalidClientTokenId(awserr.New("InvalidClientTokenId", "The security token included in the request is invalid.", nil)))
+	assert.False(t, isInvalidClientTokenId(awserr.New("AccessDenied", "Access denied", nil)))
+	assert.False(t, isInvalidClientTokenId(errors.New("some error")))
+}
+
+func TestSwamp_FetchBaseCredentials(t *testing.T) {
+	cred, err := fetchBaseCredentials(`echo '{"AccessKeyId": "some-access-key", "SecretAccessKey": "some-secret-access-key"}'`)
+	assert.NoError(t, err)
+
+	value, err := cred.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "some-access-key", value.AccessKeyID)
+	assert.Equal(t, "some-secret-access-key", value.SecretAccessKey)
+	assert.Equal(t, "", value.SessionToken)
+}
+
+func TestSwamp_FetchBaseCredentialsMissingSecret(t *testing.T) {
+	_, err := fetchBaseCredentials(`echo '{"AccessKeyId": "some-access-key"}'`)
+
+	assert.Error(t, err)
+}
+
+func TestSwamp_FormatPromptDefault(t *testing.T) {
+	config := NewSwampConfig()
+	config.tokenSerialNumber = "some-device-id"
+
+	assert.Equal(t, "Enter mfa token for some-device-id: ", formatPrompt(config, config.tokenSerialNumber))
+}
+
+func TestSwamp_FormatPromptTemplate(t *testing.T) {
+	config := NewSwampConfig()
+	config.tokenSerialNumber = "some-device-id"
+	config.promptTemplate = "OTP ({serial})> "
+
+	assert.Equal(t, "OTP (some-device-id)> ", formatPrompt(config, config.tokenSerialNumber))
+}
+
+func TestSwamp_AccountProfileName(t *testing.T) {
+	assert.Equal(t, "acct-123456789012", accountProfileName("123456789012"))
+}
+
+func TestSwamp_WarnOnExpiry(t *testing.T) {
+	start := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+	c := newFakeClock(start)
+	clock = c
+	defer func() { clock = realClock{} }()
+	expiration := start.Add(time.Hour)
+	health.SetExpiration(&expiration)
+	defer health.SetExpiration(nil)
+
+	config := NewSwampConfig()
+	config.targetDuration = 3600
+	warnOnExpiry(config)
+
+	assert.Equal(t, []time.Duration{30 * time.Minute, 30 * time.Minute}, c.sleeps)
+}
+
+func TestSwamp_SsoSourceIdentity(t *testing.T) {
+	identity, ok := ssoSourceIdentity("arn:aws:sts::1234567890:assumed-role/AWSReservedSSO_Admin_0123456789abcdef/user@example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "user@example.com", identity)
+
+	_, ok = ssoSourceIdentity("arn:aws:sts::1234567890:assumed-role/some-role/some-session")
+	assert.False(t, ok)
+
+	_, ok = ssoSourceIdentity("arn:aws:iam::1234567890:user/some-user")
+	assert.False(t, ok)
+}
+
+func TestSwamp_ExecCommand_ExecEnvCredentials(t *testing.T) {
+	creds := testCredentials()
+
+	config := NewSwampConfig()
+	config.targetProfile = "some-target-profile"
+	config.region = "eu-central-1"
+	config.execEnv = EXEC_ENV_CREDENTIALS
+	config.exec = `test -z "${AWS_PROFILE}" && test "${AWS_ACCESS_KEY_ID}" = some-access-key && test "${AWS_SESSION_TOKEN}" = some-session-token && test "${AWS_REGION}" = eu-central-1`
+
+	err := execCommand(config, creds)
+
+	assert.NoError(t, err)
+}
+
+func TestSwamp_ExecEnvironmentBoth(t *testing.T) {
+	creds := testCredentials()
+
+	config := NewSwampConfig()
+	config.targetProfile = "some-target-profile"
+	config.execEnv = EXEC_ENV_BOTH
+
+	env := execEnvironment(config, creds)
+
+	assert.Contains(t, env, "AWS_PROFILE=some-target-profile")
+	assert.Contains(t, env, "AWS_ACCESS_KEY_ID=some-access-key")
+	assert.Contains(t, env, "AWS_SECRET_ACCESS_KEY=some-secret-access-key")
+}
+
+func TestSwamp_GetTokenCodeWithTotpSecret(t *testing.T) {
+	clock = newFakeClock(time.Unix(59, 0))
+	defer func() { clock = realClock{} }()
+
+	config := NewSwampConfig()
+	config.tokenSerialNumber = "some-device-id"
+	config.totpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	assert.EqualValues(t, "287082", getTokenCode(config, config.tokenSerialNumber))
+}
+
+func TestSwamp_ExtractTokenCode(t *testing.T) {
+	assert.Equal(t, "123456", extractTokenCode("Touch your token\n123456\n"))
+	assert.Equal(t, "654321", extractTokenCode("debug: 12345678\ncode: 654321"))
+	assert.Equal(t, "no code", extractTokenCode("no code"))
+}
+
+func TestSwamp_GetTokenCodeWithMFACommandExtractCode(t *testing.T) {
+	config := NewSwampConfig()
+	config.tokenSerialNumber = "some-device-id"
+	config.mfaExec = "echo 'Touch your token'; echo 123456"
+	config.mfaExtractCode = true
+
+	assert.EqualValues(t, "123456", getTokenCode(config, config.tokenSerialNumber))
+}
+
+func TestSwamp_GetAssumeRoleSessionOptionsWithRegionOverride(t *testing.T) {
+	config := NewSwampConfig()
+	config.profile = "some-profile"
+	config.region = "eu-central-1"
+
+	assert.Equal(t, "eu-central-1", *getAssumeRoleSessionOptions(config).Config.Region)
+
+	config.assumeRoleRegion = "us-east-1"
+	options := getAssumeRoleSessionOptions(config)
+	assert.Equal(t, "us-east-1", *options.Config.Region)
+	assert.Equal(t, "some-profile", options.Profile)
+	assert.Equal(t, "eu-central-1", config.region)
+}
+
+func TestSwamp_RunErrorHook(t *testing.T) {
+	hookOutput := path.Join(os.TempDir(), "swamp-hook-test.txt")
+	defer os.Remove(hookOutput)
+
+	runErrorHook(`echo "${SWAMP_ERROR_STEP}: ${SWAMP_ERROR}" > `+hookOutput, "Error assuming role", errors.New("some error"))
+
+	b, err := ioutil.ReadFile(hookOutput)
+	assert.NoError(t, err)
+	assert.Equal(t, "Error assuming role: some error\n", string(b))
+}
+
+func TestSwamp_NewSessionOptionsWithCredentialProcess(t *testing.T) {
+	processPath := path.Join(os.TempDir(), "swamp-credential-process-test.sh")
+	defer os.Remove(processPath)
+	ioutil.WriteFile(processPath, []byte(`#!/bin/sh
+echo '{"Version": 1, "AccessKeyId": "some-access-key-id", "SecretAccessKey": "some-secret-access-key"}'
+`), 0700)
+	configPath := path.Join(os.TempDir(), "swamp-config-test")
+	defer os.Remove(configPath)
+	ioutil.WriteFile(configPath, []byte("[profile some-profile]\ncredential_process = "+processPath+"\n"), 0600)
+	os.Setenv("AWS_CONFIG_FILE", configPath)
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", path.Join(os.TempDir(), "swamp-missing-credentials-test"))
+	// credential_process runs with sh from PATH, which other tests clear
+	os.Setenv("PATH", "/bin:/usr/bin")
+	defer os.Clearenv()
+
+	profile := "some-profile"
+	region := "eu-central-1"
+	sess, err := session.NewSessionWithOptions(newSessionOptions(&profile, &region))
+	assert.NoError(t, err)
+
+	cred, err := sess.Config.Credentials.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "some-access-key-id", cred.AccessKeyID)
+}
+
+func TestSwamp_GetAssumedIdentity(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:sts::123456789012:assumed-role/admin/john.doe</Arn>
+    <UserId>AROAEXAMPLE:john.doe</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`)
+	}))
+	defer server.Close()
+	sess := session.Must(session.NewSession(&aws.Config{Endpoint: aws.String(server.URL), Region: aws.String("eu-central-1")}))
+	cred := testCredentials()
+
+	id := getAssumedIdentity(sess, cred)
+
+	assert.Equal(t, "arn:aws:sts::123456789012:assumed-role/admin/john.doe", *id.Arn)
+	assert.Equal(t, "123456789012", getAssumedAccount(sess, cred))
+	assert.Contains(t, auth, "Credential=some-access-key/")
+}
+
+func TestSwamp_RenewLimitReached(t *testing.T) {
+	config := NewSwampConfig()
+	started := time.Date(2017, 7, 6, 8, 0, 0, 0, time.UTC)
+
+	assert.False(t, renewLimitReached(config, 100, started, started.Add(100*time.Hour)))
+
+	config.renewMaxIterations = 3
+	assert.False(t, renewLimitReached(config, 2, started, started))
+	assert.True(t, renewLimitReached(config, 3, started, started))
+
+	config.renewMaxIterations = 0
+	config.renewFor = 8 * time.Hour
+	assert.False(t, renewLimitReached(config, 10, started, started.Add(8*time.Hour)))
+	assert.True(t, renewLimitReached(config, 10, started, started.Add(8*time.Hour+time.Second)))
+}
+
+func TestSwamp_ShellCommand(t *testing.T) {
+	assert.Equal(t, []string{"/bin/sh", "-c", "pass otp aws"}, shellCommand("/bin/sh", "pass otp aws"))
+	assert.Equal(t, []string{"/bin/bash", "-eu", "-c", "pass otp aws"}, shellCommand("/bin/bash -eu -c", "pass otp aws"))
+	assert.Equal(t, []string{"cmd", "/c", "otp.exe aws"}, shellCommand("cmd", "otp.exe aws"))
+	assert.Equal(t, []string{"pass", "otp", "aws"}, shellCommand("none", "pass otp aws"))
+}
+
+func TestSwamp_ExecutingMFACommandWithoutShell(t *testing.T) {
+	assert.EqualValues(t, "1234\n", fetchTokenCode("some-device-id", "/bin/echo 1234", SHELL_NONE, nil))
+}
+
+func TestSwamp_CheckBaseAccount(t *testing.T) {
+	callerId := &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/some-user"),
+	}
+
+	assert.NoError(t, checkBaseAccount("", callerId))
+	assert.NoError(t, checkBaseAccount("123456789012", callerId))
+	assert.EqualError(t, checkBaseAccount("210987654321", callerId),
+		"Base identity arn:aws:iam::123456789012:user/some-user is in account 123456789012 instead of 210987654321")
+}
+
+func TestSwamp_ExecutingMFACommandWithEnv(t *testing.T) {
+	os.Setenv("SWAMP_TEST_VAULT", "inherited")
+	defer os.Clearenv()
+
+	assert.EqualValues(t, "https://vault:8200\n", fetchTokenCode("some-device-id", "echo $SWAMP_TEST_VAULT", "/bin/sh", []string{"SWAMP_TEST_VAULT=https://vault:8200"}))
+	assert.EqualValues(t, "inherited\n", fetchTokenCode("some-device-id", "echo $SWAMP_TEST_VAULT", "/bin/sh", nil))
+}
+
+func TestSwamp_EnvExportKeepsStdoutClean(t *testing.T) {
+	credPath := path.Join(os.TempDir(), "swamp-test-stdout.ini")
+	os.Remove(credPath)
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credPath)
+	defer os.Clearenv()
+	defer os.Remove(credPath)
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	config := NewSwampConfig()
+	config.printEnvExport = true
+	assert.Equal(t, os.Stderr, messageOutput(config))
+	messages := new(strings.Builder)
+	printer.SetOutput(messages)
+	defer printer.SetOutput(os.Stdout)
+
+	cred := testCredentials()
+	cred.SetExpiration(time.Now().Add(time.Hour))
+	pw, _ := NewProfileWriter()
+	assert.NoError(t, pw.WriteProfile(cred, aws.String("some-profile"), aws.String("")))
+	assert.NoError(t, writeOutputs(config, cred))
+	w.Close()
+	assert.Contains(t, messages.String(), "Wrote session token for profile some-profile")
+
+	out, _ := ioutil.ReadAll(r)
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	assert.Len(t, lines, 5)
+	for _, line := range lines {
+		assert.Regexp(t, `^(export AWS_[A-Z_]+=\S+|unset AWS_[A-Z_]+)$`, line)
+	}
+}
+
+func TestSwamp_ResolveCallerAccount(t *testing.T) {
+	callerId := &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/some-user"),
+	}
+	config := NewSwampConfig()
+	config.targetRole = "users/Developer"
+	config.accountFromCaller = true
+
+	assert.NoError(t, resolveCallerAccount(config, callerId))
+	assert.Equal(t, "arn:aws:iam::123456789012:role/users/Developer", *config.GetRoleArn())
+
+	config.targetAccount = ""
+	config.targetRole = "some role"
+	assert.EqualError(t, resolveCallerAccount(config, callerId), "Invalid target role arn arn:aws:iam::123456789012:role/some role")
+}
+
+// A redirectTransport sends all requests to the server at url.
+type redirectTransport struct {
+	url string
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = "http", strings.TrimPrefix(rt.url, "http://")
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// sessions of swamp talk to a fake sts, the actions called are returned
+func fakeSts(t *testing.T) (*[]string, func()) {
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		actions = append(actions, r.Form.Get("Action"))
+		switch r.Form.Get("Action") {
+		case "GetCallerIdentity":
+			fmt.Fprint(w, callerIdentityResponse)
+		case "GetSessionToken":
+			fmt.Fprint(w, `<GetSessionTokenResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetSessionTokenResult>
+    <Credentials>
+      <AccessKeyId>new-access-key</AccessKeyId>
+      <SecretAccessKey>new-secret-access-key</SecretAccessKey>
+      <SessionToken>new-session-token</SessionToken>
+      <Expiration>2021-05-01T12:00:00Z</Expiration>
+    </Credentials>
+  </GetSessionTokenResult>
+</GetSessionTokenResponse>`)
+		default:
+			t.Errorf("unexpected action %s", r.Form.Get("Action"))
+		}
+	}))
+	sessionHTTPClient = &http.Client{Transport: redirectTransport{url: server.URL}}
+	return &actions, func() {
+		sessionHTTPClient = nil
+		server.Close()
+	}
+}
+
+func testSessionTokenConfig(t *testing.T, reuse bool) (*SwampConfig, *ProfileWriter, func()) {
+	credPath := path.Join(os.TempDir(), "swamp-session-token-test.ini")
+	os.Remove(credPath)
+	os.Clearenv()
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credPath)
+	os.Setenv("AWS_CONFIG_FILE", "does-not-exist")
+	os.Setenv("AWS_ACCESS_KEY_ID", "some-user-key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "some-user-secret")
+
+	config := NewSwampConfig()
+	config.region = "eu-central-1"
+	config.targetRole = "some-role"
+	config.tokenSerialNumber = "arn:aws:iam::123456789012:mfa/user"
+	config.mfaExec = "echo 123456"
+	config.intermediateReuse = reuse
+	pw, err := NewProfileWriter()
+	assert.NoError(t, err)
+	assert.NoError(t, pw.WriteProfile(testCredentials(), &config.intermediateProfile, &config.region))
+	return config, pw, func() {
+		os.Clearenv()
+		os.Remove(credPath)
+	}
+}
+
+func TestSwamp_EnsureSessionTokenProfileReusesValidToken(t *testing.T) {
+	actions, cleanupSts := fakeSts(t)
+	defer cleanupSts()
+	config, pw, cleanup := testSessionTokenConfig(t, true)
+	defer cleanup()
+
+	assert.False(t, ensureSessionTokenProfile(config, pw))
+
+	assert.Equal(t, []string{"GetCallerIdentity"}, *actions)
+}
+
+func TestSwamp_EnsureSessionTokenProfileWithoutReuse(t *testing.T) {
+	actions, cleanupSts := fakeSts(t)
+	defer cleanupSts()
+	config, pw, cleanup := testSessionTokenConfig(t, false)
+	defer cleanup()
+
+	assert.True(t, ensureSessionTokenProfile(config, pw))
+
+	assert.Equal(t, []string{"GetSessionToken"}, *actions)
+	b, err := ioutil.ReadFile(pw.credentialsPath)
+	assert.NoError(t, err)
+	assertKeyValue(t, "aws_session_token", "new-session-token", string(b))
+}
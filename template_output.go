@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// fields available in -template-file
+type templateCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+	Region          string
+	Profile         string
+}
+
+func renderCredentialsTemplate(templatePath string, cred *sts.Credentials, region, profile string) ([]byte, error) {
+	tpl, err := template.New("credentials").Option("missingkey=error").ParseFiles(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	t := templateCredentials{
+		AccessKeyId:     *cred.AccessKeyId,
+		SecretAccessKey: *cred.SecretAccessKey,
+		SessionToken:    *cred.SessionToken,
+		Region:          region,
+		Profile:         profile,
+	}
+	if cred.Expiration != nil {
+		t.Expiration = *cred.Expiration
+	}
+	buf := new(bytes.Buffer)
+	if err := tpl.ExecuteTemplate(buf, tpl.Templates()[0].Name(), t); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCredentialsTemplate(templatePath, outPath string, cred *sts.Credentials, region, profile string) error {
+	b, err := renderCredentialsTemplate(templatePath, cred, region, profile)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(outPath, 0600, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	}); err != nil {
+		return err
+	}
+	printer.Printf("Wrote credentials to %s\n", outPath)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateOutput_WriteCredentialsTemplate(t *testing.T) {
+	outPath := path.Join(os.TempDir(), "swamp-test.netrc")
+	defer os.Remove(outPath)
+
+	creds := testCredentials()
+
+	err := writeCredentialsTemplate("example/netrc.tmpl", outPath, creds, "eu-central-1", "some-profile")
+	assert.NoError(t, err)
+
+	b, _ := ioutil.ReadFile(outPath)
+	assert.Equal(t, `machine some-profile.aws.amazon.com
+  login some-access-key
+  password some-secret-access-key
+  account some-session-token
+`, string(b))
+}
+
+func TestTemplateOutput_MissingTemplate(t *testing.T) {
+	creds := testCredentials()
+
+	_, err := renderCredentialsTemplate("does-not-exists", creds, "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A totp computes time based one-time passwords as specified in rfc 6238.
+type totp struct {
+	secret    []byte
+	digits    int
+	period    int64
+	algorithm func() hash.Hash
+}
+
+// parse an otpauth:// uri or a plain base32 encoded secret
+func parseTotp(s string) (*totp, error) {
+	t := &totp{digits: 6, period: 30, algorithm: sha1.New}
+	secret := s
+	if strings.HasPrefix(s, "otpauth://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing otpauth uri: %s", err)
+		}
+		if u.Host != "totp" {
+			return nil, fmt.Errorf("Unsupported otpauth type: %s", u.Host)
+		}
+		q := u.Query()
+		secret = q.Get("secret")
+		if d := q.Get("digits"); d != "" {
+			if t.digits, err = strconv.Atoi(d); err != nil || t.digits < 6 || t.digits > 8 {
+				return nil, fmt.Errorf("Invalid otpauth digits: %s", d)
+			}
+		}
+		if p := q.Get("period"); p != "" {
+			if t.period, err = strconv.ParseInt(p, 10, 64); err != nil || t.period <= 0 {
+				return nil, fmt.Errorf("Invalid otpauth period: %s", p)
+			}
+		}
+		switch strings.ToUpper(q.Get("algorithm")) {
+		case "", "SHA1":
+		case "SHA256":
+			t.algorithm = sha256.New
+		case "SHA512":
+			t.algorithm = sha512.New
+		default:
+			return nil, fmt.Errorf("Unsupported otpauth algorithm: %s", q.Get("algorithm"))
+		}
+	}
+
+	secret = strings.ToUpper(strings.Replace(strings.TrimSpace(secret), " ", "", -1))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("Invalid totp secret")
+	}
+	t.secret = key
+	return t, nil
+}
+
+func readTotpFile(path string) (*totp, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTotp(strings.TrimSpace(string(b)))
+}
+
+func (t *totp) Code(now time.Time) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(now.Unix()/t.period))
+	mac := hmac.New(t.algorithm, t.secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < t.digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.digits, value%mod)
+}
+
+// time until the code changes
+func (t *totp) UntilNextPeriod(now time.Time) time.Duration {
+	return time.Duration(t.period-now.Unix()%t.period) * time.Second
+}
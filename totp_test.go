@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test vectors from rfc 6238, secret is "12345678901234567890"
+func TestTotp_Rfc6238(t *testing.T) {
+	totp, err := parseTotp("otpauth://totp/aws?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=8")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "94287082", totp.Code(time.Unix(59, 0)))
+	assert.Equal(t, "07081804", totp.Code(time.Unix(1111111109, 0)))
+	assert.Equal(t, "14050471", totp.Code(time.Unix(1111111111, 0)))
+}
+
+func TestTotp_PlainSecret(t *testing.T) {
+	totp, err := parseTotp("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "287082", totp.Code(time.Unix(59, 0)))
+}
+
+func TestTotp_InvalidSecret(t *testing.T) {
+	_, err := parseTotp("not base32!")
+	assert.Error(t, err)
+
+	_, err = parseTotp("otpauth://hotp/aws?secret=GEZDGNBVGY3TQOJQ")
+	assert.Error(t, err)
+}
+
+func TestTotp_InvalidDigits(t *testing.T) {
+	for _, d := range []string{"5", "9", "100", "-1", "six"} {
+		_, err := parseTotp("otpauth://totp/aws?secret=GEZDGNBVGY3TQOJQ&digits=" + d)
+		assert.EqualError(t, err, "Invalid otpauth digits: "+d)
+	}
+}
+
+func TestTotp_UntilNextPeriod(t *testing.T) {
+	totp, _ := parseTotp("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+
+	assert.Equal(t, 20*time.Second, totp.UntilNextPeriod(time.Date(2017, 7, 6, 8, 0, 10, 0, time.UTC)))
+	assert.Equal(t, 30*time.Second, totp.UntilNextPeriod(time.Date(2017, 7, 6, 8, 0, 30, 0, time.UTC)))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// print the url encoded trust policy document indented next to the caller
+func writeTrustPolicy(w io.Writer, callerArn, roleArn, document string) error {
+	decoded, err := url.QueryUnescape(document)
+	if err != nil {
+		return fmt.Errorf("Error decoding trust policy: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := json.Indent(buf, []byte(decoded), "", "  "); err != nil {
+		return fmt.Errorf("Error parsing trust policy: %s", err)
+	}
+	fmt.Fprintf(w, "Caller:      %s\n", callerArn)
+	fmt.Fprintf(w, "Target role: %s\n", roleArn)
+	fmt.Fprintln(w, "Trust policy:")
+	fmt.Fprintln(w, buf.String())
+	return nil
+}
+
+// fetch trust policy of the target role with the credentials used for assume-role
+func debugTrustPolicy(w io.Writer, config *SwampConfig) error {
+	resolveRegion(config, detectEc2Region)
+	options := getAssumeRoleSessionOptions(config)
+	sess, err := session.NewSessionWithOptions(options)
+	if err != nil {
+		return err
+	}
+	caller := getCallerId(sts.New(sess), guessCurrentProfile(config))
+	roleArn := *config.GetRoleArn()
+	document, err := fetchTrustPolicy(sess, *caller.Account, roleArn)
+	if err != nil {
+		return err
+	}
+	return writeTrustPolicy(w, *caller.Arn, roleArn, document)
+}
+
+// iam only knows roles of the caller's account, a same-named role there is not the target role
+func fetchTrustPolicy(sess *session.Session, callerAccount, roleArn string) (string, error) {
+	if account := arnAccount(roleArn); account != callerAccount {
+		return "", fmt.Errorf("Target role %s is in account %s, but the caller is in account %s. Run iam:GetRole with credentials of account %s to see its trust policy", roleArn, account, callerAccount, account)
+	}
+	output, err := iam.New(sess).GetRole(&iam.GetRoleInput{RoleName: aws.String(roleNameFromArn(roleArn))})
+	if err != nil {
+		return "", fmt.Errorf("Error fetching trust policy of %s, this needs iam:GetRole in the role's account: %s", roleArn, err)
+	}
+	return *output.Role.AssumeRolePolicyDocument, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustPolicy_WriteTrustPolicy(t *testing.T) {
+	buf := new(bytes.Buffer)
+	document := "%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Principal%22%3A%7B%22AWS%22%3A%22arn%3Aaws%3Aiam%3A%3A123456789012%3Aroot%22%7D%2C%22Action%22%3A%22sts%3AAssumeRole%22%7D%5D%7D"
+
+	assert.NoError(t, writeTrustPolicy(buf, "arn:aws:iam::123456789012:user/some-user", "arn:aws:iam::210987654321:role/admin", document))
+	assert.Equal(t, `Caller:      arn:aws:iam::123456789012:user/some-user
+Target role: arn:aws:iam::210987654321:role/admin
+Trust policy:
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "AWS": "arn:aws:iam::123456789012:root"
+      },
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}
+`, buf.String())
+
+	assert.Error(t, writeTrustPolicy(buf, "", "", "%7Bno-json"))
+}
+
+func TestTrustPolicy_CrossAccountRoleNotLookedUp(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer server.Close()
+	sess := session.Must(session.NewSession(&aws.Config{Endpoint: aws.String(server.URL), Region: aws.String("eu-central-1"),
+		Credentials: credentials.NewStaticCredentials("some-access-key", "some-secret-key", "")}))
+
+	_, err := fetchTrustPolicy(sess, "123456789012", "arn:aws:iam::210987654321:role/admin")
+
+	assert.EqualError(t, err, "Target role arn:aws:iam::210987654321:role/admin is in account 210987654321, but the caller is in account 123456789012. Run iam:GetRole with credentials of account 210987654321 to see its trust policy")
+	assert.Equal(t, 0, requests)
+}
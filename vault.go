@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type vaultSecret struct {
+	AccessKeyId     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+	Expiration      string `json:"expiration"`
+}
+
+// api url of a kv secret, kv version 2 stores data below <mount>/data/<path>
+func vaultSecretUrl(addr, path string, kvVersion int) string {
+	path = strings.Trim(path, "/")
+	if kvVersion == 2 {
+		parts := strings.SplitN(path, "/", 2)
+		if len(parts) == 2 {
+			path = parts[0] + "/data/" + parts[1]
+		}
+	}
+	return strings.TrimRight(addr, "/") + "/v1/" + path
+}
+
+func writeVaultSecret(addr, token, path string, kvVersion int, cred *sts.Credentials) error {
+	secret := vaultSecret{
+		AccessKeyId:     *cred.AccessKeyId,
+		SecretAccessKey: *cred.SecretAccessKey,
+		SessionToken:    *cred.SessionToken,
+	}
+	if cred.Expiration != nil {
+		secret.Expiration = cred.Expiration.UTC().Format(time.RFC3339)
+	}
+
+	var body interface{} = secret
+	if kvVersion == 2 {
+		body = map[string]interface{}{"data": secret}
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", vaultSecretUrl(addr, path, kvVersion), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error writing vault secret %s: %s", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Error writing vault secret %s: %s", path, resp.Status)
+	}
+
+	printer.Printf("Wrote credentials to vault secret %s\n", path)
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVault_SecretUrl(t *testing.T) {
+	assert.Equal(t, "http://vault:8200/v1/secret/data/aws/prod", vaultSecretUrl("http://vault:8200/", "secret/aws/prod", 2))
+	assert.Equal(t, "http://vault:8200/v1/secret/aws/prod", vaultSecretUrl("http://vault:8200", "/secret/aws/prod", 1))
+}
+
+func TestVault_WriteSecret(t *testing.T) {
+	var token string
+	var body map[string]map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/secret/data/aws/prod", r.URL.Path)
+		token = r.Header.Get("X-Vault-Token")
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	creds := testCredentials()
+
+	err := writeVaultSecret(server.URL, "some-vault-token", "secret/aws/prod", 2, creds)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "some-vault-token", token)
+	assert.Equal(t, "some-access-key", body["data"]["access_key_id"])
+	assert.Equal(t, "some-secret-access-key", body["data"]["secret_access_key"])
+	assert.Equal(t, "some-session-token", body["data"]["session_token"])
+}
+
+func TestVault_WriteSecretFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	creds := testCredentials()
+
+	assert.Error(t, writeVaultSecret(server.URL, "some-vault-token", "secret/aws/prod", 2, creds))
+}